@@ -0,0 +1,86 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+const (
+	//ConstraintPrimaryKey is the primary key constraint of a table
+	ConstraintPrimaryKey = "PRIMARY KEY"
+	//ConstraintUnique is a unique constraint of a table
+	ConstraintUnique = "UNIQUE"
+	//ConstraintForeignKey is a foreign key constraint of a table
+	ConstraintForeignKey = "FOREIGN KEY"
+	//ConstraintCheck is a check constraint of a table
+	ConstraintCheck = "CHECK"
+	//ConstraintExclusion is an exclusion constraint of a table
+	ConstraintExclusion = "EXCLUDE"
+	//ConstraintNotNull is a not null constraint of a table
+	ConstraintNotNull = "NOT NULL"
+)
+
+//TableInfo has the info about a table in a datastore
+type TableInfo struct {
+	//Name of the table
+	Name string
+	//Schema is the schema or the namespace in which the table is in the datastore
+	Schema string
+	//RowEstimate is the estimated no. of rows in the table as per the statistics of the datastore
+	RowEstimate int64
+	//SizeBytes is the size of the table on the disk including its indexes in bytes
+	SizeBytes int64
+	//AppendOnly is true if no rows of the table were updated or deleted as per the statistics of the datastore
+	AppendOnly bool
+}
+
+//ColumnInfo has the info about a column in a table
+type ColumnInfo struct {
+	Column
+	//DatabaseType is the data type of the column in the datastore
+	DatabaseType string
+	//Nullable is true if the column can have nulls
+	Nullable bool
+	//Default is the expression of the default value of the column. It is empty if the column has no default
+	Default string
+}
+
+//IndexInfo has the info about an index on a table
+type IndexInfo struct {
+	//Name of the index
+	Name string
+	//Columns are the columns in the index in their order. Expressions in the index are left out
+	Columns []string
+	//Unique is true if the index is unique
+	Unique bool
+	//Primary is true if the index is of the primary key
+	Primary bool
+	//Method is the access method of the index like btree or brin
+	Method string
+	//Definition is the statement creating the index
+	Definition string
+	//Auto is true if the index was created automatically using CreateAutoIndex
+	Auto bool
+}
+
+//ConstraintInfo has the info about a constraint on a table
+type ConstraintInfo struct {
+	//Name of the constraint
+	Name string
+	//Type of the constraint like ConstraintPrimaryKey, ConstraintUnique etc
+	Type string
+	//Columns are the columns constrained
+	Columns []string
+	//Definition is the definition of the constraint
+	Definition string
+}
+
+//TableDescription has the description of a table with its columns, indexes and constraints
+type TableDescription struct {
+	TableInfo
+	//Columns of the table in their order
+	Columns []ColumnInfo
+	//Indexes on the table
+	Indexes []IndexInfo
+	//Constraints on the table
+	Constraints []ConstraintInfo
+}
@@ -0,0 +1,208 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+//Compression is the compression format of a source file
+type Compression int
+
+const (
+	//CompressionNone is the source file which is not compressed
+	CompressionNone Compression = iota
+	//CompressionGzip is the source file compressed with gzip like .csv.gz
+	CompressionGzip
+	//CompressionZip is the zip archive having one or more files
+	CompressionZip
+	//CompressionZstd is the source file compressed with zstandard like .csv.zst
+	CompressionZstd
+)
+
+//compressionMagics has the magic bytes at the start of the compressed files
+var compressionMagics = []struct {
+	magic       []byte
+	compression Compression
+}{
+	{[]byte{0x1f, 0x8b}, CompressionGzip},
+	{[]byte{'P', 'K', 0x03, 0x04}, CompressionZip},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd},
+}
+
+//String returns the name of the compression format
+func (c Compression) String() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZip:
+		return "zip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return "none"
+	}
+}
+
+//DetectCompression detects the compression format of the given file from the magic bytes at its start.
+//The extension of the file is not considered
+func DetectCompression(filename string) (Compression, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return CompressionNone, err
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return CompressionNone, err
+	}
+	magic = magic[:n]
+	for _, m := range compressionMagics {
+		if bytes.HasPrefix(magic, m.magic) {
+			return m.compression, nil
+		}
+	}
+	return CompressionNone, nil
+}
+
+//ArchiveMembers returns the names of the files in the given zip archive.
+//Directories and the metadata added by the archivers like __MACOSX are left out.
+//It returns nil if the file is not a zip archive
+func ArchiveMembers(filename string) ([]string, error) {
+	c, err := DetectCompression(filename)
+	if err != nil || c != CompressionZip {
+		return nil, err
+	}
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	members := []string{}
+	for _, f := range z.File {
+		if isArchiveMember(f) {
+			members = append(members, f.Name)
+		}
+	}
+	return members, nil
+}
+
+//ArchiveMemberName returns the name of the member in an archive without its directory and extension
+func ArchiveMemberName(member string) string {
+	name := path.Base(member)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+//OpenDecompressed opens the given file for reading its decompressed content.
+//The content is decompressed while being read without writing it to the disk.
+//For zip archives, the member to be read has to be given. It can be left empty if the archive has only one member
+func OpenDecompressed(filename string, member string) (io.ReadCloser, error) {
+	/*
+	 * We will detect the compression of the file
+	 * Then we will open the file
+	 * Then we will wrap the file with the decompressor
+	 */
+	//detecting the compression
+	c, err := DetectCompression(filename)
+	if err != nil {
+		return nil, err
+	}
+	if c == CompressionZip {
+		return openArchiveMember(filename, member)
+	}
+
+	//opening the file
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	//wrapping the file with the decompressor
+	switch c {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &decompressedFile{Reader: gr, closers: []io.Closer{gr, f}}, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		rc := zr.IOReadCloser()
+		return &decompressedFile{Reader: rc, closers: []io.Closer{rc, f}}, nil
+	default:
+		return f, nil
+	}
+}
+
+//openArchiveMember opens a member in the zip archive for reading
+func openArchiveMember(filename string, member string) (io.ReadCloser, error) {
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	var found *zip.File
+	for _, f := range z.File {
+		if !isArchiveMember(f) {
+			continue
+		}
+		if len(member) == 0 && found != nil {
+			z.Close()
+			return nil, errors.New("zip archive " + filename + " has more than one member. member to be read has to be specified")
+		}
+		if len(member) == 0 || f.Name == member {
+			found = f
+		}
+	}
+	if found == nil {
+		z.Close()
+		return nil, errors.New("couldn't find the member " + member + " in the zip archive " + filename)
+	}
+	r, err := found.Open()
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+	return &decompressedFile{Reader: r, closers: []io.Closer{r, z}}, nil
+}
+
+//isArchiveMember returns true if the file in the zip archive is a member having data
+func isArchiveMember(f *zip.File) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(path.Base(f.Name), ".") {
+		return false
+	}
+	return true
+}
+
+//decompressedFile is the reader of the decompressed content which closes the decompressor and the underlying file when closed
+type decompressedFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+//Close closes the decompressor and the underlying file
+func (d *decompressedFile) Close() error {
+	var err error
+	for _, c := range d.closers {
+		if cErr := c.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
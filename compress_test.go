@@ -0,0 +1,100 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/klauspost/compress/zstd"
+)
+
+//compressedCSV is the csv written to the compressed test files
+const compressedCSV = "name,age\nanne,32\nbob,\n"
+
+func TestOpenDecompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cuttle-compress")
+	if err != nil {
+		t.Error("error while creating the test directory", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	//files are named without their extension so that the compression is detected from the content
+	files := map[string]toolkit.Compression{}
+	write := func(name string, c toolkit.Compression, fn func(w io.Writer) io.Closer) {
+		f, _ := os.Create(dir + "/" + name)
+		fn(f).Close()
+		f.Close()
+		files[dir+"/"+name] = c
+	}
+	write("plain", toolkit.CompressionNone, func(w io.Writer) io.Closer {
+		w.Write([]byte(compressedCSV))
+		return ioutil.NopCloser(nil)
+	})
+	write("gzip", toolkit.CompressionGzip, func(w io.Writer) io.Closer {
+		gw := gzip.NewWriter(w)
+		gw.Write([]byte(compressedCSV))
+		return gw
+	})
+	write("zstd", toolkit.CompressionZstd, func(w io.Writer) io.Closer {
+		zw, _ := zstd.NewWriter(w)
+		zw.Write([]byte(compressedCSV))
+		return zw
+	})
+	write("zip", toolkit.CompressionZip, func(w io.Writer) io.Closer {
+		zw := zip.NewWriter(w)
+		f, _ := zw.Create("data/sales.csv")
+		f.Write([]byte(compressedCSV))
+		zw.Create("__MACOSX/data/._sales.csv")
+		return zw
+	})
+
+	for filename, expected := range files {
+		c, err := toolkit.DetectCompression(filename)
+		if err != nil || c != expected {
+			t.Error("expected the compression of", filename, "to be", expected, "got", c, err)
+			continue
+		}
+		r, err := toolkit.OpenDecompressed(filename, "")
+		if err != nil {
+			t.Error("error while opening the compressed file", filename, err)
+			continue
+		}
+		rows, err := toolkit.NewCSVRowReader(r, toolkit.CSVDialect{})
+		if err != nil {
+			t.Error("error while reading the header of", filename, err)
+			r.Close()
+			continue
+		}
+		read := [][]interface{}{}
+		for {
+			row, err := rows.Read()
+			if err != nil {
+				break
+			}
+			read = append(read, row)
+		}
+		r.Close()
+		if !reflect.DeepEqual(rows.Header(), []string{"name", "age"}) || !reflect.DeepEqual(read, [][]interface{}{{"anne", "32"}, {"bob", nil}}) {
+			t.Error("expected the csv to be read from", filename, "got", rows.Header(), read)
+		}
+	}
+
+	members, err := toolkit.ArchiveMembers(dir + "/zip")
+	if err != nil || !reflect.DeepEqual(members, []string{"data/sales.csv"}) {
+		t.Error("expected the members of the archive to be [data/sales.csv]. got", members, err)
+		return
+	}
+	if name := toolkit.ChildTableName("upload", toolkit.ArchiveMemberName(members[0])); name != "upload_sales" {
+		t.Error("expected the table of the archive member to be upload_sales. got", name)
+	}
+}
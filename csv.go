@@ -0,0 +1,561 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//CSVDialect has the format in which a csv file is written.
+//The zero value of the dialect is the default dialect of comma separated values with double quotes, a header row and utf-8 encoding
+type CSVDialect struct {
+	//Delimiter separates the fields in a record. Defaults to comma
+	Delimiter rune
+	//Quote is the character used to quote the fields. Defaults to double quote
+	Quote rune
+	//Escape is the character used to escape the quote character inside a quoted field. Defaults to the quote character
+	Escape rune
+	//NoHeader if set means that the file doesn't have a header row
+	NoHeader bool
+	//NullString is the unquoted string representing a null value. Defaults to the empty string
+	NullString string
+	//Encoding is the character encoding of the file. Defaults to EncodingUTF8
+	Encoding string
+}
+
+const (
+	//EncodingUTF8 is the utf-8 encoding. Byte order mark if present is ignored
+	EncodingUTF8 = "UTF-8"
+	//EncodingLatin1 is the ISO-8859-1 encoding
+	EncodingLatin1 = "LATIN1"
+	//EncodingWindows1252 is the windows-1252 encoding
+	EncodingWindows1252 = "WINDOWS-1252"
+	//EncodingUTF16 is the utf-16 encoding. The byte order is identified from the byte order mark defaulting to little endian
+	EncodingUTF16 = "UTF-16"
+)
+
+//WithDefaults returns the dialect with the defaults set for the values not specified
+func (d CSVDialect) WithDefaults() CSVDialect {
+	if d.Delimiter == 0 {
+		d.Delimiter = ','
+	}
+	if d.Quote == 0 {
+		d.Quote = '"'
+	}
+	if d.Escape == 0 {
+		d.Escape = d.Quote
+	}
+	if len(d.Encoding) == 0 {
+		d.Encoding = EncodingUTF8
+	}
+	d.Encoding = strings.ToUpper(d.Encoding)
+	return d
+}
+
+//IsDefault returns true if the dialect is same as the default dialect
+func (d CSVDialect) IsDefault() bool {
+	return d.WithDefaults() == CSVDialect{}.WithDefaults()
+}
+
+//Validate validates whether the dialect is valid or not
+func (d CSVDialect) Validate() error {
+	d = d.WithDefaults()
+	if d.Delimiter == d.Quote {
+		return errors.New("delimiter and quote can't be the same")
+	}
+	if d.Delimiter == '\r' || d.Delimiter == '\n' || d.Quote == '\r' || d.Quote == '\n' {
+		return errors.New("delimiter and quote can't be a line break")
+	}
+	switch d.Encoding {
+	case EncodingUTF8, EncodingLatin1, EncodingWindows1252, EncodingUTF16:
+		return nil
+	}
+	return errors.New("unsupported encoding " + d.Encoding)
+}
+
+//CSVReader reads the records from a csv file written in a dialect
+type CSVReader struct {
+	r      *bufio.Reader
+	d      CSVDialect
+	line   int
+	field  strings.Builder
+	fields []string
+	quoted []bool
+	start  int
+	raw    []rune
+}
+
+//NewCSVReader returns a reader reading the csv from r in the given dialect.
+//The source is decoded from the encoding of the dialect
+func NewCSVReader(r io.Reader, d CSVDialect) (*CSVReader, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	d = d.WithDefaults()
+	dr, err := NewDecodingReader(r, d.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVReader{r: bufio.NewReader(dr), d: d}, nil
+}
+
+//Line returns the line number at which the last read record started
+func (c *CSVReader) Line() int {
+	return c.start
+}
+
+//Raw returns the text of the last read record as it was in the csv without the line break at the end.
+//It is available for the malformed records too
+func (c *CSVReader) Raw() string {
+	return strings.TrimRight(string(c.raw), "\r\n")
+}
+
+//Read reads a record from the csv. It returns io.EOF when there are no more records.
+//Malformed records are reported with *csv.ParseError after which the reading can continue
+func (c *CSVReader) Read() ([]string, error) {
+	if err := c.readRecord(); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), c.fields...), nil
+}
+
+//ReadRow reads a record from the csv with the unquoted fields matching the null string of the dialect as nil.
+//Rest of the fields are strings
+func (c *CSVReader) ReadRow() ([]interface{}, error) {
+	if err := c.readRecord(); err != nil {
+		return nil, err
+	}
+	row := make([]interface{}, len(c.fields))
+	for i, f := range c.fields {
+		if !c.quoted[i] && f == c.d.NullString {
+			continue
+		}
+		row[i] = f
+	}
+	return row, nil
+}
+
+func (c *CSVReader) readRecord() error {
+	/*
+	 * We will skip the empty lines
+	 * Then we will read the runes till the end of the record
+	 *		unquoted fields end at the delimiter or the line break
+	 *		quoted fields end at a quote not escaped
+	 */
+	c.fields = c.fields[:0]
+	c.quoted = c.quoted[:0]
+	c.field.Reset()
+
+	//skipping the empty lines
+	ru, err := c.readRune()
+	for err == nil && (ru == '\n' || ru == '\r') {
+		if ru == '\n' {
+			c.line++
+		}
+		ru, err = c.readRune()
+	}
+	if err != nil {
+		return err
+	}
+	startLine := c.line + 1
+	c.start = startLine
+	c.raw = append(c.raw[:0], ru)
+
+	//reading the fields in the record
+	inQuotes := false
+	quoted := false
+	for {
+		if err == io.EOF {
+			if inQuotes {
+				c.line++
+				return &csv.ParseError{StartLine: startLine, Line: c.line, Err: csv.ErrQuote}
+			}
+			c.endField(quoted)
+			c.line++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case inQuotes && ru == c.d.Escape && c.d.Escape != c.d.Quote:
+			next, nErr := c.readRune()
+			if nErr == nil && (next == c.d.Quote || next == c.d.Escape) {
+				c.field.WriteRune(next)
+			} else {
+				c.field.WriteRune(ru)
+				if nErr == nil {
+					c.unreadRune()
+				}
+			}
+		case inQuotes && ru == c.d.Quote:
+			next, nErr := c.readRune()
+			if nErr == nil && next == c.d.Quote && c.d.Escape == c.d.Quote {
+				c.field.WriteRune(next)
+			} else {
+				inQuotes = false
+				if nErr == nil {
+					c.unreadRune()
+				}
+			}
+		case inQuotes:
+			if ru == '\n' {
+				c.line++
+			}
+			c.field.WriteRune(ru)
+		case ru == c.d.Quote && c.field.Len() == 0 && !quoted:
+			inQuotes = true
+			quoted = true
+		case ru == c.d.Delimiter:
+			c.endField(quoted)
+			quoted = false
+		case ru == '\n':
+			c.endField(quoted)
+			c.line++
+			return nil
+		case ru == '\r':
+			next, nErr := c.readRune()
+			if nErr == nil && next != '\n' {
+				c.unreadRune()
+			}
+			c.endField(quoted)
+			c.line++
+			return nil
+		default:
+			c.field.WriteRune(ru)
+		}
+		ru, err = c.readRune()
+	}
+}
+
+func (c *CSVReader) readRune() (rune, error) {
+	ru, _, err := c.r.ReadRune()
+	if err == nil {
+		c.raw = append(c.raw, ru)
+	}
+	return ru, err
+}
+
+func (c *CSVReader) unreadRune() {
+	c.r.UnreadRune()
+	c.raw = c.raw[:len(c.raw)-1]
+}
+
+func (c *CSVReader) endField(quoted bool) {
+	c.fields = append(c.fields, c.field.String())
+	c.quoted = append(c.quoted, quoted)
+	c.field.Reset()
+}
+
+//CSVRowReader reads the rows from a csv as a RowReader. The header of the csv if present is read upfront.
+//The fields are mapped to the columns by their position in the row
+type CSVRowReader struct {
+	r      *CSVReader
+	header []string
+	eof    bool
+}
+
+//NewCSVRowReader returns a row reader reading the csv from r in the given dialect
+func NewCSVRowReader(r io.Reader, d CSVDialect) (*CSVRowReader, error) {
+	cr, err := NewCSVReader(r, d)
+	if err != nil {
+		return nil, err
+	}
+	rows := &CSVRowReader{r: cr}
+	if d.NoHeader {
+		return rows, nil
+	}
+	rows.header, err = cr.Read()
+	if err == io.EOF {
+		rows.eof = true
+		return rows, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//Header returns the header of the csv. It will be nil if the dialect has no header
+func (c *CSVRowReader) Header() []string {
+	return c.header
+}
+
+//Columns returns the columns in the header of the csv. The data type of the columns is string.
+//Columns with an empty name are named by their position like column_1
+func (c *CSVRowReader) Columns() []interpreter.ColumnNode {
+	columns := make([]interpreter.ColumnNode, len(c.header))
+	for i, h := range c.header {
+		name := strings.TrimSpace(h)
+		if len(name) == 0 {
+			name = "column_" + strconv.Itoa(i+1)
+		}
+		columns[i] = interpreter.ColumnNode{Name: name, DataType: interpreter.DataTypeString}
+	}
+	return columns
+}
+
+//Line returns the line number at which the last read row started
+func (c *CSVRowReader) Line() int {
+	return c.r.Line()
+}
+
+//Raw returns the text of the last read row as it was in the csv
+func (c *CSVRowReader) Raw() string {
+	return c.r.Raw()
+}
+
+//Read reads the next row from the csv. Malformed rows are reported with *csv.ParseError after which the reading can continue.
+//It returns io.EOF when there are no more rows
+func (c *CSVRowReader) Read() ([]interface{}, error) {
+	if c.eof {
+		return nil, io.EOF
+	}
+	return c.r.ReadRow()
+}
+
+//CSVWriter writes the rows to a csv in a dialect. The output is always utf-8 encoded
+type CSVWriter struct {
+	w *bufio.Writer
+	d CSVDialect
+}
+
+//NewCSVWriter returns a writer writing the csv to w in the given dialect
+func NewCSVWriter(w io.Writer, d CSVDialect) (*CSVWriter, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	d = d.WithDefaults()
+	if d.Encoding != EncodingUTF8 {
+		return nil, errors.New("csv can only be written in " + EncodingUTF8 + " encoding. got " + d.Encoding)
+	}
+	return &CSVWriter{w: bufio.NewWriter(w), d: d}, nil
+}
+
+//Write writes a row to the csv. Nil values are written as the null string of the dialect
+func (c *CSVWriter) Write(row []interface{}) error {
+	for i, v := range row {
+		if i > 0 {
+			c.w.WriteRune(c.d.Delimiter)
+		}
+		str, null := FormatValue(v)
+		if null {
+			c.w.WriteString(c.d.NullString)
+			continue
+		}
+		if !c.needsQuotes(str) {
+			c.w.WriteString(str)
+			continue
+		}
+		c.w.WriteRune(c.d.Quote)
+		for _, ru := range str {
+			if ru == c.d.Quote || (ru == c.d.Escape && c.d.Escape != c.d.Quote) {
+				c.w.WriteRune(c.d.Escape)
+			}
+			c.w.WriteRune(ru)
+		}
+		c.w.WriteRune(c.d.Quote)
+	}
+	_, err := c.w.WriteRune('\n')
+	return err
+}
+
+//WriteStrings writes a record of strings to the csv
+func (c *CSVWriter) WriteStrings(record []string) error {
+	row := make([]interface{}, len(record))
+	for i, v := range record {
+		row[i] = v
+	}
+	return c.Write(row)
+}
+
+//Flush writes the buffered rows to the underlying writer
+func (c *CSVWriter) Flush() error {
+	return c.w.Flush()
+}
+
+func (c *CSVWriter) needsQuotes(str string) bool {
+	if str == c.d.NullString {
+		return true
+	}
+	for _, ru := range str {
+		if ru == c.d.Delimiter || ru == c.d.Quote || ru == c.d.Escape || ru == '\r' || ru == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+//JoinRow joins the values in the row with the delimiter of the dialect. Nulls are written as the null string of the dialect.
+//It is used to report the row as it was read and the values aren't quoted
+func (d CSVDialect) JoinRow(row []interface{}) string {
+	d = d.WithDefaults()
+	values := make([]string, len(row))
+	for i, v := range row {
+		value, null := FormatValue(v)
+		if null {
+			value = d.NullString
+		}
+		values[i] = value
+	}
+	return strings.Join(values, string(d.Delimiter))
+}
+
+//FormatValue formats a value read from a datastore or a file as a string. It returns true if the value is null
+func FormatValue(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return val, false
+	case *string:
+		if val == nil {
+			return "", true
+		}
+		return *val, false
+	case []byte:
+		if val == nil {
+			return "", true
+		}
+		return string(val), false
+	case time.Time:
+		return val.Format(time.RFC3339Nano), false
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), false
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32), false
+	default:
+		return fmt.Sprint(val), false
+	}
+}
+
+//NewDecodingReader returns a reader that decodes the source from the given encoding to utf-8
+func NewDecodingReader(r io.Reader, encoding string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	switch strings.ToUpper(encoding) {
+	case "", EncodingUTF8:
+		//skipping the byte order mark
+		if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+			br.Discard(3)
+		}
+		return br, nil
+	case EncodingLatin1:
+		return &decodingReader{decode: func() (rune, error) {
+			b, err := br.ReadByte()
+			return rune(b), err
+		}}, nil
+	case EncodingWindows1252:
+		return &decodingReader{decode: func() (rune, error) {
+			b, err := br.ReadByte()
+			if err == nil && b >= 0x80 && b <= 0x9f {
+				return windows1252[b-0x80], nil
+			}
+			return rune(b), err
+		}}, nil
+	case EncodingUTF16:
+		return newUTF16Reader(br), nil
+	}
+	return nil, errors.New("unsupported encoding " + encoding)
+}
+
+//windows1252 has the runes for the bytes from 0x80 to 0x9f which differ from latin1 in windows-1252
+var windows1252 = [32]rune{
+	'€', '\ufffd', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\ufffd', 'Ž', '\ufffd',
+	'\ufffd', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\ufffd', 'ž', 'Ÿ',
+}
+
+//decodingReader is an io.Reader giving the utf-8 encoding of the runes decoded from a source
+type decodingReader struct {
+	decode  func() (rune, error)
+	pending []byte
+	err     error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(d.pending) > 0 {
+			c := copy(p[n:], d.pending)
+			d.pending = d.pending[c:]
+			n += c
+			continue
+		}
+		if d.err != nil {
+			break
+		}
+		ru, err := d.decode()
+		if err != nil {
+			d.err = err
+			continue
+		}
+		var buf [utf8.UTFMax]byte
+		d.pending = buf[:utf8.EncodeRune(buf[:], ru)]
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, d.err
+}
+
+func newUTF16Reader(br *bufio.Reader) *decodingReader {
+	bigEndian := false
+	if bom, err := br.Peek(2); err == nil {
+		if bom[0] == 0xfe && bom[1] == 0xff {
+			bigEndian = true
+			br.Discard(2)
+		} else if bom[0] == 0xff && bom[1] == 0xfe {
+			br.Discard(2)
+		}
+	}
+	readUnit := func() (rune, error) {
+		var b [2]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return utf8.RuneError, nil
+			}
+			return 0, err
+		}
+		if bigEndian {
+			return rune(b[0])<<8 | rune(b[1]), nil
+		}
+		return rune(b[1])<<8 | rune(b[0]), nil
+	}
+	//pending is the unit read after a high surrogate which wasn't a low surrogate
+	var pending *rune
+	return &decodingReader{decode: func() (rune, error) {
+		var u rune
+		var err error
+		if pending != nil {
+			u, pending = *pending, nil
+		} else {
+			u, err = readUnit()
+		}
+		if err != nil || !utf16.IsSurrogate(u) {
+			return u, err
+		}
+		l, err := readUnit()
+		if err != nil {
+			return utf8.RuneError, nil
+		}
+		ru := utf16.DecodeRune(u, l)
+		if ru == utf8.RuneError && !utf16.IsSurrogate(l) {
+			pending = &l
+		}
+		return ru, nil
+	}}
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestCSVReader(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     []byte
+		dialect toolkit.CSVDialect
+		rows    [][]interface{}
+	}{
+		{
+			name:    "semicolon with null marker",
+			src:     []byte("item;price\r\n\"Käse; alt\";NA\nBrot;\"NA\"\n"),
+			dialect: toolkit.CSVDialect{Delimiter: ';', NullString: "NA"},
+			rows:    [][]interface{}{{"item", "price"}, {"Käse; alt", nil}, {"Brot", "NA"}},
+		},
+		{
+			name:    "latin1 with backslash escape",
+			src:     []byte("caf\xe9|'it\\'s'\n"),
+			dialect: toolkit.CSVDialect{Delimiter: '|', Quote: '\'', Escape: '\\', Encoding: toolkit.EncodingLatin1},
+			rows:    [][]interface{}{{"café", "it's"}},
+		},
+		{
+			name:    "windows-1252",
+			src:     []byte("\x80,\x93quoted\x94\n"),
+			dialect: toolkit.CSVDialect{Encoding: toolkit.EncodingWindows1252},
+			rows:    [][]interface{}{{"€", "“quoted”"}},
+		},
+		{
+			name:    "utf-16 with byte order mark",
+			src:     []byte{0xff, 0xfe, 'a', 0, '\t', 0, 'b', 0, '\n', 0},
+			dialect: toolkit.CSVDialect{Delimiter: '\t', Encoding: toolkit.EncodingUTF16},
+			rows:    [][]interface{}{{"a", "b"}},
+		},
+	}
+	for _, c := range cases {
+		r, err := toolkit.NewCSVReader(bytes.NewReader(c.src), c.dialect)
+		if err != nil {
+			t.Error(c.name, "error while creating the reader", err)
+			continue
+		}
+		rows := [][]interface{}{}
+		for {
+			row, err := r.ReadRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Error(c.name, "error while reading the row", err)
+				break
+			}
+			rows = append(rows, row)
+		}
+		if !reflect.DeepEqual(rows, c.rows) {
+			t.Error(c.name, "expected", c.rows, "got", rows)
+		}
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var b strings.Builder
+	w, err := toolkit.NewCSVWriter(&b, toolkit.CSVDialect{})
+	if err != nil {
+		t.Error("error while creating the writer", err)
+		return
+	}
+	w.Write([]interface{}{"a,b", nil, "", `say "hi"`, 4})
+	w.Flush()
+	expected := `"a,b",,"","say ""hi""",4` + "\n"
+	if b.String() != expected {
+		t.Error("expected", expected, "got", b.String())
+	}
+}
+
+func TestCSVDialectJoinRow(t *testing.T) {
+	d := toolkit.CSVDialect{Delimiter: ';', NullString: "NULL"}
+	if joined := d.JoinRow([]interface{}{"apple", nil, "1"}); joined != "apple;NULL;1" {
+		t.Error("expected the row to be joined as apple;NULL;1. got", joined)
+	}
+}
@@ -7,6 +7,8 @@
 package toolkit
 
 import (
+	"io"
+
 	"github.com/cuttle-ai/brain/log"
 	"github.com/cuttle-ai/octopus/interpreter"
 )
@@ -26,13 +28,93 @@ type Datastore interface {
 	//DumpCSV will dump the given csv file to the datastore.
 	//Default behaviour of the method will be to replace the existing data in the datastore.
 	//But if appendData flag is set, then existing data won't be removed instead new data will be appended to it.
+	//doScp is not used anymore as the file is transferred using the transport of the datastore.
 	DumpCSV(filename string, tablename string, columns []interpreter.ColumnNode, appendData bool, createTable bool, doScp bool, logger log.Log) error
-	//DeleteTable will delete the given table in the datastore
+	//DumpCSVWithOptions will dump the given csv file to the datastore as per the dump options.
+	//It returns the report of the dump having the no. of rows loaded and the rows that failed the validation.
+	//Files compressed with gzip, zstd or a zip archive with a single file are decompressed while being dumped
+	DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts DumpOptions, logger log.Log) (DumpResult, error)
+	//DumpCSVArchive will dump the csv files in the given zip archive to the datastore with each file in its own table.
+	//It returns the results of the dump by the table name
+	DumpCSVArchive(filename string, tablename string, columns []interpreter.ColumnNode, opts DumpOptions, logger log.Log) (map[string]DumpResult, error)
+	//DumpCSVResumable will dump the given csv file to the datastore in chunks with a checkpoint committed along with each chunk.
+	//If the dump is interrupted, calling it again with the same file resumes it from the last committed chunk.
+	//The table is changed only after all the chunks are committed so that it has the same data as a single dump
+	DumpCSVResumable(filename string, tablename string, columns []interpreter.ColumnNode, opts DumpOptions, logger log.Log) (DumpResult, error)
+	//DumpRows will dump the rows read from the reader to the datastore as per the dump options.
+	//The values in a row are in the same order as the columns
+	DumpRows(rows RowReader, tablename string, columns []interpreter.ColumnNode, opts DumpOptions, logger log.Log) (DumpResult, error)
+	//DumpJSONL will dump the given newline delimited json file to the datastore as per the dump options.
+	//Keys of the json objects are mapped to the columns with the nested objects flattened using JSONPathSeparator.
+	//If the overflow column is set in the options, the structure not mapped to the columns is stored in it as json
+	DumpJSONL(filename string, tablename string, columns []interpreter.ColumnNode, opts DumpOptions, logger log.Log) (DumpResult, error)
+	//DumpParquet will dump the given parquet file to the datastore as per the dump options.
+	//If no columns are given, the columns in the parquet file with their data types mapped from the logical types are used
+	DumpParquet(filename string, tablename string, columns []interpreter.ColumnNode, opts DumpOptions, logger log.Log) (DumpResult, error)
+	//DumpExcel will dump the sheet or all the sheets in the given excel workbook to the datastore as per the dump options.
+	//When all the sheets are dumped, each sheet goes to its own table named using ChildTableName.
+	//It returns the results of the dump by the table name
+	DumpExcel(filename string, tablename string, columns []interpreter.ColumnNode, excel ExcelOptions, opts DumpOptions, logger log.Log) (map[string]DumpResult, error)
+	//ExportCSV will export the given table to the writer as csv as per the export options
+	ExportCSV(w io.Writer, tablename string, opts ExportOptions) error
+	//ExportQueryCSV will export the result of the given read only query to the writer as csv as per the export options
+	ExportQueryCSV(w io.Writer, opts ExportOptions, query string, args ...interface{}) error
+	//ExportParquet will export the given table to the writer in the parquet format
+	ExportParquet(w io.Writer, tablename string) error
+	//RestorePreviousTable restores the previous version of a table replaced using a shadow table.
+	//The restored version is swapped with the current one so that the restore can be undone by restoring again
+	RestorePreviousTable(tablename string) error
+	//DropPreviousTable drops the previous version of a table replaced using a shadow table if exists
+	DropPreviousTable(tablename string) error
+	//DeleteTable will delete the given table in the datastore.
+	//It returns a TableError with ErrTableNotFound if the table doesn't exist
 	DeleteTable(tablename string) error
+	//DropTableIfExists will delete the given table in the datastore if it exists
+	DropTableIfExists(tablename string) error
+	//RenameTable will rename the table. It returns a TableError with ErrTableNotFound if the table doesn't exist
+	//and with ErrTableExists if a table with the new name already exists
+	RenameTable(from string, to string) error
+	//CloneTable will create a new table with the same schema as the table and if required with its data.
+	//It returns a TableError with ErrTableNotFound if the table doesn't exist and with ErrTableExists if the new table already exists
+	CloneTable(from string, to string, withData bool) error
+	//TruncateTable will remove all the rows in the table. It returns a TableError with ErrTableNotFound if the table doesn't exist
+	TruncateTable(tablename string) error
+	//AddColumn will add the column to the table. It returns a TableError with ErrTableNotFound if the table doesn't exist
+	//and with ErrColumnExists if the table already has the column
+	AddColumn(tablename string, column interpreter.ColumnNode) error
+	//DropColumn will remove the column from the table. It returns a TableError with ErrTableNotFound if the table doesn't exist
+	//and with ErrColumnNotFound if the table doesn't have the column
+	DropColumn(tablename string, colName string) error
+	//RenameColumn will rename the column in the table. It returns a TableError with ErrTableNotFound if the table doesn't exist,
+	//with ErrColumnNotFound if the table doesn't have the column and with ErrColumnExists if the table already has a column with the new name
+	RenameColumn(tablename string, from string, to string) error
+	//ReorderColumns will change the order of the columns in the table. The given columns are moved to the front in the given order
+	//and the rest of the columns follow them in their existing order. It returns a TableError with ErrTableNotFound if the table doesn't exist
+	//and with ErrColumnNotFound if the table doesn't have one of the columns
+	ReorderColumns(tablename string, colNames []string) error
+	//CreateAutoIndex will create an index on the column of the table with the given access method if not exists and return its name.
+	//If the table already has such an index on just the column, its name is returned.
+	//The index is built without blocking the reads and writes on the table.
+	//It is reported as an automatic index while describing the table
+	CreateAutoIndex(tablename string, colName string, method string) (string, error)
+	//DropIndex will drop the index if exists without blocking the reads and writes on its table
+	DropIndex(indexname string) error
 	//Exec can execute a query and return the response as the array of interfaces
 	Exec(query string, args ...interface{}) ([]map[string]interface{}, error)
+	//ListTables returns the tables in the datastore with their estimated no. of rows and size
+	ListTables() ([]TableInfo, error)
+	//TableExists returns true if the table exists in the datastore
+	TableExists(tablename string) (bool, error)
+	//DescribeTable returns the description of the table with its columns, indexes and constraints
+	DescribeTable(tablename string) (TableDescription, error)
+	//Dialect returns the dialect of the datastore for generating the queries that can be run on it
+	Dialect() Dialect
 	//GetColumnTypes returns the list of columns and their data types for a given table
 	GetColumnTypes(tableName string) ([]Column, error)
 	//ChangeColumnTypeToDate changes the data type of the given column to date with the provided date format
 	ChangeColumnTypeToDate(tableName string, colName string, dateFormat string) error
+	//ChangeColumnTypeToDateWithRejects changes the data type of the given column to date with the provided date format.
+	//Values that can't be parsed are copied to the reject table along with their row identifier and set to null in the converted column.
+	//It returns the number of rows rejected
+	ChangeColumnTypeToDateWithRejects(tableName string, colName string, dateFormat string, rejectTable string) (int64, error)
 }
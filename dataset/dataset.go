@@ -55,7 +55,18 @@ func IdentifyDimensions(l log.Log, conn *gorm.DB, cols []models.Node, table mode
 	tb := table.TableNode()
 	for i := 0; i < len(columns); i++ {
 		//get the unique values the columns are holding
-		result, err := dStore.Exec("SELECT COUNT(DISTINCT(\"" + columns[i].Name + "\")) FROM \"" + tb.Name + "\"")
+		query := toolkit.AggregateQuery{
+			Table:    tb,
+			Measures: []toolkit.Measure{{Column: columns[i], Aggregation: toolkit.AggregateCountDistinct}},
+		}
+		qStr, args, err := query.Build(dStore.Dialect())
+		if err != nil {
+			//error while building the query to find the count of the unique values in the column
+			l.Error("error while building the query to find the count of the unique values in the column", columns[i].Name, "from the table", tb.Name)
+			l.Error(err)
+			continue
+		}
+		result, err := dStore.Exec(qStr, args...)
 		if err != nil {
 			//error while querying the datastore to find the count of the unique values in the column
 			l.Error("error while querying the datastore to find the count of the unique values in the column", columns[i].Name, "from the table", tb.Name)
@@ -90,13 +101,31 @@ func IdentifyDimensions(l log.Log, conn *gorm.DB, cols []models.Node, table mode
 	return nil
 }
 
+//RejectTableName returns the name of the table to which the rejected values of a dataset's table are copied
+func RejectTableName(tableName string) string {
+	return tableName + "_rejects"
+}
+
 //ConvertDates will identify the dates in the datsets and update the same in the db
 func ConvertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Node, dSer services.Service, dt *models.Dataset) error {
+	_, err := convertDates(l, conn, cols, table, dSer, dt, "")
+	return err
+}
+
+//ConvertDatesWithRejects will identify the dates in the datsets and update the same in the db.
+//Unlike ConvertDates, the values that can't be parsed as date won't fail the conversion.
+//They are copied to the reject table of the dataset and set to null. It returns the number of rows rejected
+func ConvertDatesWithRejects(l log.Log, conn *gorm.DB, cols []models.Node, table models.Node, dSer services.Service, dt *models.Dataset) (int64, error) {
+	return convertDates(l, conn, cols, table, dSer, dt, RejectTableName(table.TableNode().Name))
+}
+
+func convertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Node, dSer services.Service, dt *models.Dataset, rejectTable string) (int64, error) {
 	/*
 	 * We will first get the columns having date data type
 	 * We will get the datastore in which the table is stored in
 	 * Then we will get the datatype of the columns in db
 	 * If the data type in db is different, then we will change the data type
+	 *		if the reject table is given, the values that can't be parsed are quarantined
 	 * The we will update the table's default date field if not available with one
 	 */
 	//getting the columns having the date data type
@@ -112,7 +141,7 @@ func ConvertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Nod
 		}
 	}
 	if len(columns) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	l.Info("have", len(columns), "to that are of date type in", tN.Name)
@@ -121,7 +150,7 @@ func ConvertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Nod
 	if err != nil {
 		//error while getting the datastore
 		l.Error("error while getting the datastore", dSer.ID)
-		return err
+		return 0, err
 	}
 
 	//getting the column data types
@@ -129,7 +158,7 @@ func ConvertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Nod
 	if err != nil {
 		//error while getting the column data types
 		l.Error("error while getting the datatypes of the columns of tha table", tN.Name)
-		return err
+		return 0, err
 	}
 
 	//checking the cols with different data type
@@ -149,22 +178,29 @@ func ConvertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Nod
 
 	//if the len of columns to be changed is zero, don't go forward
 	if len(toBeChanged) == 0 {
-		return nil
+		return 0, nil
 	}
+	var rejected int64
 	for _, v := range toBeChanged {
-		err := dStore.ChangeColumnTypeToDate(tN.Name, v.Name, v.DateFormat)
+		if len(rejectTable) == 0 {
+			err = dStore.ChangeColumnTypeToDate(tN.Name, v.Name, v.DateFormat)
+		} else {
+			var r int64
+			r, err = dStore.ChangeColumnTypeToDateWithRejects(tN.Name, v.Name, v.DateFormat, rejectTable)
+			rejected += r
+		}
 		if err != nil {
 			//error while converting the data type of the column
 			l.Error("error while converting the data type to date for the column", v.Name, tN.Name)
-			return err
+			return rejected, err
 		}
 	}
-	l.Info("altered the column types from text to date", tN.Name)
+	l.Info("altered the column types from text to date", tN.Name, "rejected no. of rows:-", rejected)
 
 	//now we update the first column as default date
 	if len(tN.DefaultDateFieldUID) != 0 {
 		//we already have a default date field
-		return nil
+		return rejected, nil
 	}
 	colNode := colMap[toBeChanged[0].Name]
 	l.Info("updating the default date column of the table", tN.Name, "to", colNode.Name)
@@ -174,24 +210,339 @@ func ConvertDates(l log.Log, conn *gorm.DB, cols []models.Node, table models.Nod
 	if err != nil {
 		//error while updating the table with default date
 		l.Error("error while updating the table with default date", colNode.Name, tN.Name)
+		return rejected, err
+	}
+
+	return rejected, nil
+}
+
+//IndexDataset will create the indexes on the dimension columns and the default date field of the table in the dataset.
+//The default date field of a large append only table is indexed using a block range index, rest are indexed using btree.
+//The indexes created earlier whose columns are no more dimensions or the default date field are dropped
+func IndexDataset(l log.Log, conn *gorm.DB, cols []models.Node, table models.Node, dSer services.Service, dt *models.Dataset) error {
+	/*
+	 * We will get the datastore in which the table is stored in
+	 * Then we will get the description of the table with its existing indexes
+	 * Then we will find the columns to be indexed
+	 * Then we will plan the indexes to be created and dropped
+	 * Then we will drop the stale indexes
+	 * Finally we will create the new indexes
+	 */
+	//getting the datastore
+	tN := table.TableNode()
+	l.Info("going to index the dimensions and the default date field of the dataset table", tN.Name)
+	dStore, err := dSer.Datastore()
+	if err != nil {
+		//error while getting the datastore
+		l.Error("error while getting the datastore", dSer.ID)
+		return err
+	}
+
+	//getting the description of the table
+	desc, err := dStore.DescribeTable(tN.Name)
+	if err != nil {
+		//error while describing the table
+		l.Error("error while getting the description of the table", tN.Name, "for indexing it")
+		return err
+	}
+
+	//finding the columns to be indexed
+	required := []toolkit.AutoIndex{}
+	dims := []toolkit.AutoIndex{}
+	for _, v := range cols {
+		iN := v.ColumnNode()
+		if len(tN.DefaultDateFieldUID) != 0 && iN.UID == tN.DefaultDateFieldUID {
+			required = append(required, toolkit.AutoIndex{Column: iN.Name, Method: toolkit.AutoIndexMethod(interpreter.DataTypeDate, desc.RowEstimate, desc.AppendOnly)})
+			continue
+		}
+		if iN.Dimension {
+			dims = append(dims, toolkit.AutoIndex{Column: iN.Name, Method: toolkit.IndexMethodBTree})
+		}
+	}
+	required = append(required, dims...)
+
+	//planning the indexes to be created and dropped
+	create, drop := toolkit.PlanAutoIndexes(desc.Indexes, required)
+	l.Info("have", len(create), "indexes to be created and", len(drop), "indexes to be dropped for the table", tN.Name)
+
+	//dropping the stale indexes
+	for _, i := range drop {
+		err = dStore.DropIndex(i.Name)
+		if err != nil {
+			//error while dropping the stale index
+			l.Error("error while dropping the index", i.Name, "of the table", tN.Name)
+			return err
+		}
+	}
+
+	//creating the new indexes
+	for _, i := range create {
+		name, err := dStore.CreateAutoIndex(tN.Name, i.Column, i.Method)
+		if err != nil {
+			//error while creating the index
+			l.Error("error while creating the", i.Method, "index on the column", i.Column, "of the table", tN.Name)
+			return err
+		}
+		l.Info("created the", i.Method, "index", name, "on the column", i.Column, "of the table", tN.Name)
+	}
+
+	return nil
+}
+
+//AddColumn will add the column to the table of the dataset in the datastore and store its node in the db.
+//It returns the node created for the column
+func AddColumn(l log.Log, conn *gorm.DB, table models.Node, dSer services.Service, dt *models.Dataset, col interpreter.ColumnNode) (models.Node, error) {
+	/*
+	 * We will get the datastore in which the table is stored in
+	 * Then we will add the column to the table
+	 * Then we will create the node of the column in the db
+	 * Then we will add the column to the table node
+	 */
+	//getting the datastore
+	tN := table.TableNode()
+	l.Info("going to add the column", col.Name, "to the dataset table", tN.Name)
+	dStore, err := dSer.Datastore()
+	if err != nil {
+		//error while getting the datastore
+		l.Error("error while getting the datastore", dSer.ID)
+		return models.Node{}, err
+	}
+
+	//adding the column to the table
+	err = dStore.AddColumn(tN.Name, col)
+	if err != nil {
+		//error while adding the column to the table
+		l.Error("error while adding the column", col.Name, "to the table", tN.Name)
+		return models.Node{}, err
+	}
+
+	//creating the node of the column
+	col.PUID = tN.UID
+	node := models.Node{DatasetID: dt.ID, PUID: table.UID}.FromColumn(col)
+	err = conn.Create(&node).Error
+	if err != nil {
+		//error while creating the node of the column
+		l.Error("error while creating the node of the column", col.Name, "of the table", tN.Name)
+		return node, err
+	}
+
+	//adding the column to the table node
+	tN.Columns = append(tN.Columns, node.ColumnNode())
+	_, err = dt.UpdateTable(conn, table.FromTable(tN))
+	if err != nil {
+		//error while adding the column to the table node
+		l.Error("error while adding the column", col.Name, "to the columns of the table", tN.Name)
+		return node, err
+	}
+
+	l.Info("added the column", col.Name, "to the dataset table", tN.Name)
+	return node, nil
+}
+
+//DropColumn will remove the column from the table of the dataset in the datastore and delete its node from the db.
+//The column is removed from the table node. If the column is the default date field of the table, the table is updated to not have one
+func DropColumn(l log.Log, conn *gorm.DB, col models.Node, table models.Node, dSer services.Service, dt *models.Dataset) error {
+	/*
+	 * We will get the datastore in which the table is stored in
+	 * Then we will drop the column from the table
+	 * Then we will delete the node of the column from the db
+	 * Then we will remove the column from the table node and if required its default date field
+	 */
+	//getting the datastore
+	tN := table.TableNode()
+	cN := col.ColumnNode()
+	l.Info("going to drop the column", cN.Name, "from the dataset table", tN.Name)
+	dStore, err := dSer.Datastore()
+	if err != nil {
+		//error while getting the datastore
+		l.Error("error while getting the datastore", dSer.ID)
+		return err
+	}
+
+	//dropping the column from the table
+	err = dStore.DropColumn(tN.Name, cN.Name)
+	if err != nil {
+		//error while dropping the column from the table
+		l.Error("error while dropping the column", cN.Name, "from the table", tN.Name)
+		return err
+	}
+
+	//deleting the node of the column
+	err = conn.Delete(&col).Error
+	if err != nil {
+		//error while deleting the node of the column
+		l.Error("error while deleting the node of the column", cN.Name, "of the table", tN.Name)
+		return err
+	}
+
+	//removing the column from the table node
+	columns := []interpreter.ColumnNode{}
+	for _, c := range tN.Columns {
+		if c.Name != cN.Name {
+			columns = append(columns, c)
+		}
+	}
+	tN.Columns = columns
+	if tN.DefaultDateFieldUID == cN.UID {
+		l.Info("removing the default date column", cN.Name, "of the table", tN.Name)
+		tN.DefaultDateField = nil
+		tN.DefaultDateFieldUID = ""
+	}
+	_, err = dt.UpdateTable(conn, table.FromTable(tN))
+	if err != nil {
+		//error while removing the column from the table node
+		l.Error("error while removing the column", cN.Name, "from the columns of the table", tN.Name)
 		return err
 	}
 
+	l.Info("dropped the column", cN.Name, "from the dataset table", tN.Name)
+	return nil
+}
+
+//RenameColumn will rename the column in the table of the dataset in the datastore and update the name in its node in the db.
+//The column is also renamed in the table node. It returns the updated node of the column
+func RenameColumn(l log.Log, conn *gorm.DB, col models.Node, table models.Node, dSer services.Service, dt *models.Dataset, name string) (models.Node, error) {
+	/*
+	 * We will get the datastore in which the table is stored in
+	 * Then we will rename the column in the table
+	 * Then we will update the node of the column in the db
+	 * Then we will rename the column in the table node and if required its default date field
+	 */
+	//getting the datastore
+	tN := table.TableNode()
+	cN := col.ColumnNode()
+	l.Info("going to rename the column", cN.Name, "to", name, "in the dataset table", tN.Name)
+	dStore, err := dSer.Datastore()
+	if err != nil {
+		//error while getting the datastore
+		l.Error("error while getting the datastore", dSer.ID)
+		return col, err
+	}
+
+	//renaming the column in the table
+	err = dStore.RenameColumn(tN.Name, cN.Name, name)
+	if err != nil {
+		//error while renaming the column in the table
+		l.Error("error while renaming the column", cN.Name, "to", name, "in the table", tN.Name)
+		return col, err
+	}
+
+	//updating the node of the column
+	from := cN.Name
+	cN.Name = name
+	updated, err := dt.UpdateColumns(l, conn, []models.Node{col.FromColumn(cN)})
+	if err != nil {
+		//error while updating the name in the node of the column
+		l.Error("error while updating the name of the column", name, "of the table", tN.Name)
+		return col, err
+	}
+	if len(updated) != 0 {
+		col = updated[0]
+	}
+
+	//renaming the column in the table node
+	for i, c := range tN.Columns {
+		if c.Name == from {
+			tN.Columns[i].Name = name
+		}
+	}
+	if tN.DefaultDateFieldUID == cN.UID {
+		tN.DefaultDateField = &cN
+	}
+	_, err = dt.UpdateTable(conn, table.FromTable(tN))
+	if err != nil {
+		//error while renaming the column in the table node
+		l.Error("error while renaming the column", from, "to", name, "in the columns of the table", tN.Name)
+		return col, err
+	}
+
+	l.Info("renamed the column to", name, "in the dataset table", tN.Name)
+	return col, nil
+}
+
+//ReorderColumns will change the order of the columns in the table of the dataset in the datastore to the order of the given columns.
+//The order of the columns in the table node is also updated in the db
+func ReorderColumns(l log.Log, conn *gorm.DB, cols []models.Node, table models.Node, dSer services.Service, dt *models.Dataset) error {
+	/*
+	 * We will get the datastore in which the table is stored in
+	 * Then we will reorder the columns in the table
+	 * Then we will update the order of the columns in the table node
+	 */
+	//getting the datastore
+	tN := table.TableNode()
+	l.Info("going to reorder the columns in the dataset table", tN.Name)
+	dStore, err := dSer.Datastore()
+	if err != nil {
+		//error while getting the datastore
+		l.Error("error while getting the datastore", dSer.ID)
+		return err
+	}
+
+	//reordering the columns in the table
+	columns := make([]interpreter.ColumnNode, len(cols))
+	names := make([]string, len(cols))
+	for i, v := range cols {
+		columns[i] = v.ColumnNode()
+		names[i] = columns[i].Name
+	}
+	err = dStore.ReorderColumns(tN.Name, names)
+	if err != nil {
+		//error while reordering the columns in the table
+		l.Error("error while reordering the columns in the table", tN.Name)
+		return err
+	}
+
+	//updating the order of the columns in the table node
+	//the columns that weren't given follow the given columns in their existing order
+	given := map[string]bool{}
+	ordered := []interpreter.ColumnNode{}
+	for _, c := range columns {
+		if !given[c.Name] {
+			given[c.Name] = true
+			ordered = append(ordered, c)
+		}
+	}
+	for _, c := range tN.Columns {
+		if !given[c.Name] {
+			ordered = append(ordered, c)
+		}
+	}
+	tN.Columns = ordered
+	_, err = dt.UpdateTable(conn, table.FromTable(tN))
+	if err != nil {
+		//error while updating the order of the columns in the table node
+		l.Error("error while updating the order of the columns of the table", tN.Name)
+		return err
+	}
+
+	l.Info("reordered the columns in the dataset table", tN.Name)
 	return nil
 }
 
 //OptimizeDatasetMetadata will optimize metadata associated with a dataset
 //It will find the dimension columns in the dataset
 //It will also try to identify the date columns in the datasets
+//Finally it will index the dimensions and the default date field of the dataset
 func OptimizeDatasetMetadata(l log.Log, conn *gorm.DB, id uint, dSer services.Service, userID uint) error {
+	return OptimizeDatasetMetadataWithObserver(l, conn, id, dSer, userID, nil)
+}
+
+//OptimizeDatasetMetadataWithObserver will optimize metadata associated with a dataset like OptimizeDatasetMetadata.
+//Each step of the optimization is reported to the observer as an operation on the table of the dataset
+//along with the operations the steps run on the datastore like the data type conversions.
+//The steps identifying the dimensions and indexing the dataset are reported with the estimated no. of rows in the table
+//and the step converting the dates with the no. of rows rejected
+func OptimizeDatasetMetadataWithObserver(l log.Log, conn *gorm.DB, id uint, dSer services.Service, userID uint, o toolkit.Observer) error {
 	/*
 	 * We will get the dataset info from the db
 	 * Then we will get the columns in the dataset
-	 * Then we will get the table in the dataset
+	 * Then we will get the table in the dataset and its no. of rows
 	 * Then we will identify the dimensions in the dataset
 	 * Then we will convert the dates in the dataset
+	 * Then we will index the dimensions and the default date field with the updated columns and table
 	 */
 	dt := &models.Dataset{Model: gorm.Model{ID: id}, UserID: userID}
+	dSer.Observer = o
 	l.Info("going to optimize the dataset metadata for", dt.ID)
 
 	//getting the dataset info from the database
@@ -217,9 +568,12 @@ func OptimizeDatasetMetadata(l log.Log, conn *gorm.DB, id uint, dSer services.Se
 		l.Error("error while finding the table in the dataset from the db")
 		return err
 	}
+	rows := tableRows(l, dSer, table.TableNode().Name)
 
 	//identify the dimensions in the dataset
+	span := toolkit.StartOperation(o, toolkit.OperationIdentifyDimensions, table.TableNode().Name)
 	err = IdentifyDimensions(l, conn, cols, table, dSer, dt)
+	span.Finish(rows, err)
 	if err != nil {
 		//error while identifying the dimension columns in the dataset
 		l.Error("error while identifying the dimension columns in the dataset")
@@ -227,13 +581,56 @@ func OptimizeDatasetMetadata(l log.Log, conn *gorm.DB, id uint, dSer services.Se
 	}
 
 	//convert the dates in the dataset
-	err = ConvertDates(l, conn, cols, table, dSer, dt)
+	span = toolkit.StartOperation(o, toolkit.OperationConvertDates, table.TableNode().Name)
+	rejected, err := convertDates(l, conn, cols, table, dSer, dt, "")
+	span.Finish(rejected, err)
 	if err != nil {
 		//error while converting the date columns in the dataset
 		l.Error("error while converting the date columns in the dataset")
 		return err
 	}
 
+	//get the updated columns and table for indexing the dataset
+	cols, err = dt.GetColumns(conn)
+	if err != nil {
+		//error while finding the updated columns in the dataset
+		l.Error("error while finding the updated columns in the dataset from the db")
+		return err
+	}
+	table, err = dt.GetTable(conn)
+	if err != nil {
+		//error while finding the updated table in the dataset
+		l.Error("error while finding the updated table in the dataset from the db")
+		return err
+	}
+
+	//index the dimensions and the default date field in the dataset
+	span = toolkit.StartOperation(o, toolkit.OperationIndexDataset, table.TableNode().Name)
+	err = IndexDataset(l, conn, cols, table, dSer, dt)
+	span.Finish(rows, err)
+	if err != nil {
+		//error while indexing the dataset
+		l.Error("error while indexing the dimensions and the default date field in the dataset")
+		return err
+	}
+
 	l.Info("successfully optimized the dataset metadata for", dt.ID)
 	return nil
 }
+
+//tableRows returns the estimated no. of rows in the table of the dataset. It is zero if the table couldn't be described
+func tableRows(l log.Log, dSer services.Service, tablename string) int64 {
+	dStore, err := dSer.Datastore()
+	if err != nil {
+		//error while getting the datastore
+		l.Error("error while getting the datastore", dSer.ID)
+		return 0
+	}
+	desc, err := dStore.DescribeTable(tablename)
+	if err != nil {
+		//error while describing the table
+		l.Error("error while getting the description of the table", tablename, "for finding its no. of rows")
+		return 0
+	}
+	return desc.RowEstimate
+}
@@ -0,0 +1,382 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package cache has a datastore caching the results of the read only queries run on another datastore.
+//The cached results of a table are invalidated when the table is changed through the datastore
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//DefaultTTL is the time for which a result is cached if not specified in the options
+const DefaultTTL = time.Minute
+
+//DefaultMaxEntries is the maximum no. of results cached if not specified in the options
+const DefaultMaxEntries = 1000
+
+//anyTable is the table on which the results of the queries whose tables couldn't be found depend on
+const anyTable = "*"
+
+//Options has the options for caching the results of the queries
+type Options struct {
+	//TTL is the time for which a result is cached. Defaults to DefaultTTL
+	TTL time.Duration
+	//MaxEntries is the maximum no. of results cached. The least recently used results are evicted beyond it. Defaults to DefaultMaxEntries
+	MaxEntries int
+	//MaxRows is the maximum no. of rows in a result for caching it. Zero caches the results of any size
+	MaxRows int
+	//Now returns the current time. Defaults to time.Now
+	Now func() time.Time
+}
+
+//entry is a result cached
+type entry struct {
+	key     string
+	tables  []string
+	results []map[string]interface{}
+	expires time.Time
+}
+
+//Datastore wraps a datastore caching the results of the read only queries run using Exec.
+//Results are cached by the query normalized and its arguments. The results of the tables changed
+//by dumping the data, deleting, renaming or altering them through the datastore are invalidated.
+//Queries other than the read only ones are run on the wrapped datastore and invalidate all the cached results.
+//Changes made to the tables without going through the datastore are seen only after the results expire.
+//The tables of a query are found from the query itself, so the results read through a view aren't invalidated
+//when the tables of the view are changed and are seen only after they expire
+type Datastore struct {
+	toolkit.Datastore
+	opts    Options
+	m       sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	tables  map[string]map[string]bool
+	//gens has the no. of times the results of each table were invalidated
+	gens map[string]uint64
+	//gen is the no. of times all the results were invalidated
+	gen uint64
+}
+
+//NewDatastore returns a datastore caching the results of the queries run on the given datastore
+func NewDatastore(d toolkit.Datastore, opts Options) *Datastore {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Datastore{
+		Datastore: d,
+		opts:      opts,
+		entries:   map[string]*list.Element{},
+		lru:       list.New(),
+		tables:    map[string]map[string]bool{},
+		gens:      map[string]uint64{},
+	}
+}
+
+//Exec returns the cached result of the read only query if available else runs the query on the wrapped datastore.
+//Queries other than the read only ones invalidate all the cached results
+func (d *Datastore) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	/*
+	 * We will first find whether the query is read only
+	 * Then we will return the cached result if not expired
+	 * Else we will run the query and cache its result if its tables weren't invalidated while running it
+	 */
+	//finding whether the query is read only
+	if !toolkit.ReadOnlyQuery(query) {
+		results, err := d.Datastore.Exec(query, args...)
+		d.Invalidate()
+		return results, err
+	}
+
+	//returning the cached result
+	tokens := toolkit.TokenizeQuery(query)
+	key := cacheKey(tokens, args)
+	if results, ok := d.get(key); ok {
+		return results, nil
+	}
+
+	//running the query and caching its result
+	tablenames := tables(tokens)
+	d.m.Lock()
+	gen := d.generation(tablenames)
+	d.m.Unlock()
+	results, err := d.Datastore.Exec(query, args...)
+	if err != nil {
+		return results, err
+	}
+	if d.opts.MaxRows == 0 || len(results) <= d.opts.MaxRows {
+		d.put(key, tablenames, gen, results)
+	}
+	return copyResults(results), nil
+}
+
+//Len returns the no. of results cached
+func (d *Datastore) Len() int {
+	d.m.Lock()
+	defer d.m.Unlock()
+	return d.lru.Len()
+}
+
+//Invalidate removes all the cached results
+func (d *Datastore) Invalidate() {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.entries = map[string]*list.Element{}
+	d.lru.Init()
+	d.tables = map[string]map[string]bool{}
+	d.gen++
+}
+
+//InvalidateTables removes the cached results of the queries reading the given tables
+//and the queries whose tables couldn't be found
+func (d *Datastore) InvalidateTables(tablenames ...string) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	for _, t := range append(tablenames, anyTable) {
+		d.gens[strings.ToLower(t)]++
+		for key := range d.tables[strings.ToLower(t)] {
+			if el, ok := d.entries[key]; ok {
+				d.remove(el)
+			}
+		}
+	}
+}
+
+//get returns the cached result for the key if not expired
+func (d *Datastore) get(key string) ([]map[string]interface{}, bool) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	el, ok := d.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !d.opts.Now().Before(e.expires) {
+		d.remove(el)
+		return nil, false
+	}
+	d.lru.MoveToFront(el)
+	return copyResults(e.results), true
+}
+
+//generation returns the generation of the results of the tables. It changes whenever the results of any of the tables are invalidated.
+//The caller must hold the lock
+func (d *Datastore) generation(tablenames []string) uint64 {
+	gen := d.gen + d.gens[anyTable]
+	for _, t := range tablenames {
+		gen += d.gens[strings.ToLower(t)]
+	}
+	return gen
+}
+
+//put caches the result for the key evicting the least recently used results beyond the maximum no. of entries.
+//The result isn't cached if the generation of its tables has changed since the query was run as it may be stale
+func (d *Datastore) put(key string, tablenames []string, gen uint64, results []map[string]interface{}) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.generation(tablenames) != gen {
+		return
+	}
+	if el, ok := d.entries[key]; ok {
+		d.remove(el)
+	}
+	if len(tablenames) == 0 {
+		tablenames = []string{anyTable}
+	}
+	e := &entry{key: key, tables: tablenames, results: results, expires: d.opts.Now().Add(d.opts.TTL)}
+	d.entries[key] = d.lru.PushFront(e)
+	for _, t := range tablenames {
+		t = strings.ToLower(t)
+		if d.tables[t] == nil {
+			d.tables[t] = map[string]bool{}
+		}
+		d.tables[t][key] = true
+	}
+	for d.lru.Len() > d.opts.MaxEntries {
+		d.remove(d.lru.Back())
+	}
+}
+
+//remove removes the cached result. The caller must hold the lock
+func (d *Datastore) remove(el *list.Element) {
+	e := d.lru.Remove(el).(*entry)
+	delete(d.entries, e.key)
+	for _, t := range e.tables {
+		t = strings.ToLower(t)
+		delete(d.tables[t], e.key)
+		if len(d.tables[t]) == 0 {
+			delete(d.tables, t)
+		}
+	}
+}
+
+//copyResults copies the results so that the cached results aren't changed by the callers
+func copyResults(results []map[string]interface{}) []map[string]interface{} {
+	if results == nil {
+		return nil
+	}
+	copied := make([]map[string]interface{}, len(results))
+	for i, row := range results {
+		c := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			if s, ok := v.(*string); ok && s != nil {
+				val := *s
+				v = &val
+			}
+			c[k] = v
+		}
+		copied[i] = c
+	}
+	return copied
+}
+
+//resultTables returns the given tables along with the tables in the results of a dump
+func resultTables(results map[string]toolkit.DumpResult, tablenames ...string) []string {
+	for t := range results {
+		tablenames = append(tablenames, t)
+	}
+	return tablenames
+}
+
+//DumpCSV dumps the csv file using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DumpCSV(filename string, tablename string, columns []interpreter.ColumnNode, appendData bool, createTable bool, doScp bool, logger log.Log) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.DumpCSV(filename, tablename, columns, appendData, createTable, doScp, logger)
+}
+
+//DumpCSVWithOptions dumps the csv file using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	defer d.InvalidateTables(tablename, toolkit.PreviousTableName(tablename))
+	return d.Datastore.DumpCSVWithOptions(filename, tablename, columns, opts, logger)
+}
+
+//DumpCSVArchive dumps the csv files in the archive using the wrapped datastore and invalidates the cached results of the tables
+func (d *Datastore) DumpCSVArchive(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	results, err := d.Datastore.DumpCSVArchive(filename, tablename, columns, opts, logger)
+	d.InvalidateTables(resultTables(results, tablename)...)
+	return results, err
+}
+
+//DumpCSVResumable dumps the csv file in chunks using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DumpCSVResumable(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	defer d.InvalidateTables(tablename, toolkit.PreviousTableName(tablename))
+	return d.Datastore.DumpCSVResumable(filename, tablename, columns, opts, logger)
+}
+
+//DumpRows dumps the rows using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DumpRows(rows toolkit.RowReader, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	defer d.InvalidateTables(tablename, toolkit.PreviousTableName(tablename))
+	return d.Datastore.DumpRows(rows, tablename, columns, opts, logger)
+}
+
+//DumpJSONL dumps the json lines file using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DumpJSONL(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	defer d.InvalidateTables(tablename, toolkit.PreviousTableName(tablename))
+	return d.Datastore.DumpJSONL(filename, tablename, columns, opts, logger)
+}
+
+//DumpParquet dumps the parquet file using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DumpParquet(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	defer d.InvalidateTables(tablename, toolkit.PreviousTableName(tablename))
+	return d.Datastore.DumpParquet(filename, tablename, columns, opts, logger)
+}
+
+//DumpExcel dumps the excel workbook using the wrapped datastore and invalidates the cached results of the tables
+func (d *Datastore) DumpExcel(filename string, tablename string, columns []interpreter.ColumnNode, excel toolkit.ExcelOptions, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	results, err := d.Datastore.DumpExcel(filename, tablename, columns, excel, opts, logger)
+	d.InvalidateTables(resultTables(results, tablename)...)
+	return results, err
+}
+
+//RestorePreviousTable restores the previous version of the table using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) RestorePreviousTable(tablename string) error {
+	defer d.InvalidateTables(tablename, toolkit.PreviousTableName(tablename))
+	return d.Datastore.RestorePreviousTable(tablename)
+}
+
+//DropPreviousTable drops the previous version of the table using the wrapped datastore and invalidates the cached results of it
+func (d *Datastore) DropPreviousTable(tablename string) error {
+	defer d.InvalidateTables(toolkit.PreviousTableName(tablename))
+	return d.Datastore.DropPreviousTable(tablename)
+}
+
+//DeleteTable deletes the table using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DeleteTable(tablename string) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.DeleteTable(tablename)
+}
+
+//DropTableIfExists deletes the table using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DropTableIfExists(tablename string) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.DropTableIfExists(tablename)
+}
+
+//RenameTable renames the table using the wrapped datastore and invalidates the cached results of both the names
+func (d *Datastore) RenameTable(from string, to string) error {
+	defer d.InvalidateTables(from, to)
+	return d.Datastore.RenameTable(from, to)
+}
+
+//CloneTable clones the table using the wrapped datastore and invalidates the cached results of the new table
+func (d *Datastore) CloneTable(from string, to string, withData bool) error {
+	defer d.InvalidateTables(to)
+	return d.Datastore.CloneTable(from, to, withData)
+}
+
+//TruncateTable truncates the table using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) TruncateTable(tablename string) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.TruncateTable(tablename)
+}
+
+//AddColumn adds the column using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) AddColumn(tablename string, column interpreter.ColumnNode) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.AddColumn(tablename, column)
+}
+
+//DropColumn drops the column using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) DropColumn(tablename string, colName string) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.DropColumn(tablename, colName)
+}
+
+//RenameColumn renames the column using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) RenameColumn(tablename string, from string, to string) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.RenameColumn(tablename, from, to)
+}
+
+//ReorderColumns reorders the columns using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) ReorderColumns(tablename string, colNames []string) error {
+	defer d.InvalidateTables(tablename)
+	return d.Datastore.ReorderColumns(tablename, colNames)
+}
+
+//ChangeColumnTypeToDate changes the column type using the wrapped datastore and invalidates the cached results of the table
+func (d *Datastore) ChangeColumnTypeToDate(tableName string, colName string, dateFormat string) error {
+	defer d.InvalidateTables(tableName)
+	return d.Datastore.ChangeColumnTypeToDate(tableName, colName, dateFormat)
+}
+
+//ChangeColumnTypeToDateWithRejects changes the column type using the wrapped datastore
+//and invalidates the cached results of the table and the reject table
+func (d *Datastore) ChangeColumnTypeToDateWithRejects(tableName string, colName string, dateFormat string, rejectTable string) (int64, error) {
+	defer d.InvalidateTables(tableName, rejectTable)
+	return d.Datastore.ChangeColumnTypeToDateWithRejects(tableName, colName, dateFormat, rejectTable)
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/cache"
+)
+
+//countingDatastore counts the queries run on it
+type countingDatastore struct {
+	toolkit.Datastore
+	queries int
+	//running is called while running a query if set
+	running func()
+}
+
+func (c *countingDatastore) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	c.queries++
+	if c.running != nil {
+		c.running()
+	}
+	count := "42"
+	return []map[string]interface{}{{"count": &count}}, nil
+}
+
+func (c *countingDatastore) DeleteTable(tablename string) error {
+	return nil
+}
+
+func TestDatastore(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := &countingDatastore{}
+	c := cache.NewDatastore(d, cache.Options{TTL: time.Minute, MaxEntries: 2, Now: func() time.Time { return now }})
+
+	//same query formatted differently is served from the cache
+	c.Exec(`SELECT COUNT(*) FROM "sales" WHERE region = $1`, "south")
+	res, _ := c.Exec("select count(*)\n  from \"sales\" where REGION = $1;", "south")
+	if d.queries != 1 {
+		t.Error("expected the query to be run once. ran", d.queries, "times")
+		return
+	}
+	if count, _ := res[0]["count"].(*string); count == nil || *count != "42" {
+		t.Error("expected the cached count 42. got", res)
+		return
+	}
+	c.Exec(`SELECT COUNT(*) FROM "sales" WHERE region = $1`, "north")
+	if d.queries != 2 {
+		t.Error("expected the query with different arguments to be run. ran", d.queries, "times")
+		return
+	}
+
+	//changing the table invalidates its results
+	c.Exec(`SELECT * FROM customers`)
+	c.DeleteTable("sales")
+	if c.Len() != 1 {
+		t.Error("expected only the result of the customers table to be cached. got", c.Len(), "results")
+		return
+	}
+
+	//results expire after the ttl
+	now = now.Add(time.Minute)
+	c.Exec(`SELECT * FROM customers`)
+	if d.queries != 4 {
+		t.Error("expected the expired query to be run again. ran", d.queries, "times")
+		return
+	}
+
+	//queries other than the read only ones are never cached
+	c.Exec(`DELETE FROM customers`)
+	if c.Len() != 0 {
+		t.Error("expected no results to be cached after a write. got", c.Len(), "results")
+	}
+}
+
+func TestDatastoreInvalidatedWhileRunning(t *testing.T) {
+	d := &countingDatastore{}
+	c := cache.NewDatastore(d, cache.Options{})
+	d.running = func() {
+		c.DeleteTable("sales")
+	}
+	c.Exec(`SELECT COUNT(*) FROM sales`)
+	if c.Len() != 0 {
+		t.Error("expected the result read while the table was changed not to be cached. got", c.Len(), "results")
+		return
+	}
+	d.running = nil
+	c.Exec(`SELECT COUNT(*) FROM sales`)
+	if c.Len() != 1 {
+		t.Error("expected the result to be cached. got", c.Len(), "results")
+	}
+}
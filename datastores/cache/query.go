@@ -0,0 +1,110 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+//normalize returns the query normalized for using it as the key of the cache.
+//Whitespaces and comments are left out and the keywords are upper cased so that the same query formatted differently has the same key
+func normalize(tokens []toolkit.QueryToken) string {
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		switch {
+		case t.Quoted:
+			parts[i] = `"` + strings.Replace(t.Value, `"`, `""`, -1) + `"`
+		case t.Literal:
+			parts[i] = `'` + strings.Replace(t.Value, `'`, `''`, -1) + `'`
+		default:
+			parts[i] = strings.ToUpper(t.Value)
+		}
+	}
+	return strings.TrimRight(strings.Join(parts, " "), " ;")
+}
+
+//cacheKey returns the key of the query and its arguments in the cache
+func cacheKey(tokens []toolkit.QueryToken, args []interface{}) string {
+	var strB strings.Builder
+	strB.WriteString(normalize(tokens))
+	for _, arg := range args {
+		strB.WriteString(fmt.Sprintf("\x00%T:%v", arg, arg))
+	}
+	return strB.String()
+}
+
+//tables returns the names of the tables read by the query. The schema of the qualified names is left out
+func tables(tokens []toolkit.QueryToken) []string {
+	names := []string{}
+	for i := 0; i < len(tokens); i++ {
+		k := tokens[i].Keyword()
+		if k != "FROM" && k != "JOIN" {
+			continue
+		}
+		for {
+			name, next := tableName(tokens, i+1)
+			if len(name) == 0 {
+				break
+			}
+			names = append(names, name)
+			i = next
+			if k == "JOIN" {
+				break
+			}
+			//skipping the alias of the table in the list of tables
+			if i < len(tokens) && tokens[i].Keyword() == "AS" {
+				i++
+			}
+			if i < len(tokens) && isName(tokens[i]) {
+				i++
+			}
+			if i >= len(tokens) || tokens[i].Value != "," {
+				break
+			}
+		}
+	}
+	return names
+}
+
+//tableName returns the possibly qualified table name starting at the token at i and the index of the token after it
+func tableName(tokens []toolkit.QueryToken, i int) (string, int) {
+	name := ""
+	for i < len(tokens) && isName(tokens[i]) {
+		name = tokens[i].Value
+		if !tokens[i].Quoted {
+			name = strings.ToLower(name)
+		}
+		i++
+		if i >= len(tokens) || tokens[i].Value != "." {
+			break
+		}
+		i++
+	}
+	return name, i
+}
+
+//isName returns true if the token can be the name of a table or an alias
+func isName(t toolkit.QueryToken) bool {
+	if t.Quoted {
+		return true
+	}
+	if t.Literal || len(t.Value) == 0 {
+		return false
+	}
+	r := []rune(t.Value)[0]
+	if !unicode.IsLetter(r) && r != '_' {
+		return false
+	}
+	switch t.Keyword() {
+	case "SELECT", "WHERE", "GROUP", "ORDER", "LIMIT", "OFFSET", "HAVING", "UNION", "EXCEPT", "INTERSECT",
+		"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "NATURAL", "ON", "USING", "LATERAL", "WINDOW", "FETCH", "AS":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package observe has a datastore reporting the operations run on another datastore to an observer
+//and an observer exporting the metrics of the operations in the prometheus text format
+package observe
+
+import (
+	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//Datastore wraps a datastore reporting the queries, the dumps along with their phases
+//and the data type conversions run on it to the observer.
+//The postgres datastore can report them itself to the observer set on it, in which case it needn't be wrapped
+type Datastore struct {
+	toolkit.Datastore
+	o toolkit.Observer
+}
+
+//NewDatastore returns a datastore reporting the operations run on the given datastore to the observer
+func NewDatastore(d toolkit.Datastore, o toolkit.Observer) *Datastore {
+	return &Datastore{Datastore: d, o: o}
+}
+
+//Exec runs the query on the wrapped datastore reporting it with the no. of rows returned
+func (d *Datastore) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	span := toolkit.StartOperation(d.o, toolkit.OperationExec, "")
+	results, err := d.Datastore.Exec(query, args...)
+	span.Finish(int64(len(results)), err)
+	return results, err
+}
+
+//DumpCSV dumps the csv file as per the flags using DumpCSVWithOptions of the wrapped datastore so that the phases of the dump are reported
+func (d *Datastore) DumpCSV(filename string, tablename string, columns []interpreter.ColumnNode, appendData bool, createTable bool, doScp bool, logger log.Log) error {
+	_, err := d.DumpCSVWithOptions(filename, tablename, columns, toolkit.DumpOptions{
+		AppendData:  appendData,
+		CreateTable: createTable,
+		DoScp:       doScp,
+	}, logger)
+	return err
+}
+
+//DumpCSVWithOptions dumps the csv file using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(d.o, toolkit.OperationDumpCSV, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return d.Datastore.DumpCSVWithOptions(filename, tablename, columns, opts, logger)
+	})
+}
+
+//DumpCSVArchive dumps the csv files in the archive using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpCSVArchive(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	return toolkit.ObserveDumps(d.o, toolkit.OperationDumpCSVArchive, tablename, opts, func(opts toolkit.DumpOptions) (map[string]toolkit.DumpResult, error) {
+		return d.Datastore.DumpCSVArchive(filename, tablename, columns, opts, logger)
+	})
+}
+
+//DumpCSVResumable dumps the csv file in chunks using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpCSVResumable(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(d.o, toolkit.OperationDumpCSVResumable, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return d.Datastore.DumpCSVResumable(filename, tablename, columns, opts, logger)
+	})
+}
+
+//DumpRows dumps the rows using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpRows(rows toolkit.RowReader, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(d.o, toolkit.OperationDumpRows, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return d.Datastore.DumpRows(rows, tablename, columns, opts, logger)
+	})
+}
+
+//DumpJSONL dumps the json lines file using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpJSONL(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(d.o, toolkit.OperationDumpJSONL, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return d.Datastore.DumpJSONL(filename, tablename, columns, opts, logger)
+	})
+}
+
+//DumpParquet dumps the parquet file using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpParquet(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(d.o, toolkit.OperationDumpParquet, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return d.Datastore.DumpParquet(filename, tablename, columns, opts, logger)
+	})
+}
+
+//DumpExcel dumps the excel workbook using the wrapped datastore reporting the dump and its phases
+func (d *Datastore) DumpExcel(filename string, tablename string, columns []interpreter.ColumnNode, excel toolkit.ExcelOptions, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	return toolkit.ObserveDumps(d.o, toolkit.OperationDumpExcel, tablename, opts, func(opts toolkit.DumpOptions) (map[string]toolkit.DumpResult, error) {
+		return d.Datastore.DumpExcel(filename, tablename, columns, excel, opts, logger)
+	})
+}
+
+//ChangeColumnTypeToDate changes the data type of the column using the wrapped datastore reporting the conversion
+func (d *Datastore) ChangeColumnTypeToDate(tableName string, colName string, dateFormat string) error {
+	span := toolkit.StartOperation(d.o, toolkit.OperationChangeColumnTypeToDate, tableName)
+	err := d.Datastore.ChangeColumnTypeToDate(tableName, colName, dateFormat)
+	span.Finish(0, err)
+	return err
+}
+
+//ChangeColumnTypeToDateWithRejects changes the data type of the column using the wrapped datastore
+//reporting the conversion with the no. of rows rejected
+func (d *Datastore) ChangeColumnTypeToDateWithRejects(tableName string, colName string, dateFormat string, rejectTable string) (int64, error) {
+	span := toolkit.StartOperation(d.o, toolkit.OperationChangeColumnTypeToDate, tableName)
+	rejected, err := d.Datastore.ChangeColumnTypeToDateWithRejects(tableName, colName, dateFormat, rejectTable)
+	span.Finish(rejected, err)
+	return rejected, err
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package observe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+//DefaultBuckets are the upper bounds of the buckets of the duration histograms in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+//MetricsPrefix is the prefix of the names of the metrics exported
+const MetricsPrefix = "dbtoolkit_"
+
+//seriesKey identifies the metrics of an operation or a phase of it
+type seriesKey struct {
+	operation string
+	phase     string
+}
+
+//series has the metrics of an operation or a phase of it
+type series struct {
+	inFlight int64
+	ok       int64
+	errors   int64
+	rows     int64
+	buckets  []int64
+	sum      float64
+	count    int64
+}
+
+//Metrics observes the operations on the datastores and keeps their counts, the rows affected and the histograms of their durations
+//by the operation and the phase. They can be exported in the prometheus text format.
+//The table isn't used as a label to keep the no. of series bounded
+type Metrics struct {
+	m       sync.Mutex
+	buckets []float64
+	series  map[seriesKey]*series
+}
+
+//NewMetrics returns the metrics with the duration histograms having the DefaultBuckets
+func NewMetrics() *Metrics {
+	return &Metrics{buckets: DefaultBuckets, series: map[seriesKey]*series{}}
+}
+
+//get returns the series of the event. The caller must hold the lock
+func (m *Metrics) get(e toolkit.Event) *series {
+	key := seriesKey{operation: e.Operation, phase: string(e.Phase)}
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{buckets: make([]int64, len(m.buckets))}
+		m.series[key] = s
+	}
+	return s
+}
+
+//OperationStarted adds the operation to the operations in flight
+func (m *Metrics) OperationStarted(e toolkit.Event) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.get(e).inFlight++
+}
+
+//OperationFinished counts the operation with its status, the rows affected and its duration
+func (m *Metrics) OperationFinished(e toolkit.Event) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	s := m.get(e)
+	s.inFlight--
+	if e.Err != nil {
+		s.errors++
+	} else {
+		s.ok++
+	}
+	s.rows += e.Rows
+	seconds := e.Duration.Seconds()
+	for i, b := range m.buckets {
+		if seconds <= b {
+			s.buckets[i]++
+		}
+	}
+	s.sum += seconds
+	s.count++
+}
+
+//WritePrometheus writes the metrics in the prometheus text format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	/*
+	 * We will first sort the series so that the output is stable
+	 * Then we will write the operations in flight and the counters
+	 * Finally we will write the histograms of the durations
+	 */
+	//sorting the series
+	m.m.Lock()
+	defer m.m.Unlock()
+	keys := make([]seriesKey, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].phase < keys[j].phase
+	})
+	bw := bufio.NewWriter(w)
+
+	//writing the operations in flight and the counters
+	writeHeader(bw, "operations_in_flight", "gauge", "No. of operations running on the datastores")
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%soperations_in_flight{%s} %d\n", MetricsPrefix, k.labels(), m.series[k].inFlight)
+	}
+	writeHeader(bw, "operations_total", "counter", "No. of operations finished on the datastores by their status")
+	for _, k := range keys {
+		s := m.series[k]
+		fmt.Fprintf(bw, "%soperations_total{%s,status=\"ok\"} %d\n", MetricsPrefix, k.labels(), s.ok)
+		fmt.Fprintf(bw, "%soperations_total{%s,status=\"error\"} %d\n", MetricsPrefix, k.labels(), s.errors)
+	}
+	writeHeader(bw, "operation_rows_total", "counter", "No. of rows affected, loaded or returned by the operations on the datastores")
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%soperation_rows_total{%s} %d\n", MetricsPrefix, k.labels(), m.series[k].rows)
+	}
+
+	//writing the histograms of the durations
+	writeHeader(bw, "operation_duration_seconds", "histogram", "Time taken by the operations on the datastores in seconds")
+	for _, k := range keys {
+		s := m.series[k]
+		for i, b := range m.buckets {
+			fmt.Fprintf(bw, "%soperation_duration_seconds_bucket{%s,le=\"%s\"} %d\n", MetricsPrefix, k.labels(), strconv.FormatFloat(b, 'g', -1, 64), s.buckets[i])
+		}
+		fmt.Fprintf(bw, "%soperation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", MetricsPrefix, k.labels(), s.count)
+		fmt.Fprintf(bw, "%soperation_duration_seconds_sum{%s} %s\n", MetricsPrefix, k.labels(), strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "%soperation_duration_seconds_count{%s} %d\n", MetricsPrefix, k.labels(), s.count)
+	}
+	return bw.Flush()
+}
+
+//ServeHTTP serves the metrics in the prometheus text format so that they can be scraped
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
+
+//labels returns the labels of the series
+func (k seriesKey) labels() string {
+	return `operation="` + escapeLabel(k.operation) + `",phase="` + escapeLabel(k.phase) + `"`
+}
+
+//writeHeader writes the help and the type of a metric
+func writeHeader(w io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", MetricsPrefix, name, help, MetricsPrefix, name, metricType)
+}
+
+//escapeLabel escapes the backslashes, the double quotes and the new lines in a label value
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
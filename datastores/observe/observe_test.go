@@ -0,0 +1,64 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package observe_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/observe"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//phasedDatastore goes through the phases of a dump and fails the queries
+type phasedDatastore struct {
+	toolkit.Datastore
+}
+
+func (p phasedDatastore) DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	tracker := toolkit.NewProgressTracker(opts)
+	tracker.Phase(toolkit.PhaseCreate)
+	tracker.Phase(toolkit.PhaseCopy)
+	tracker.AddRows(3)
+	tracker.Done()
+	tracker.Phase(toolkit.PhaseCleanup)
+	tracker.Done()
+	return toolkit.DumpResult{RowsLoaded: 3}, nil
+}
+
+func (p phasedDatastore) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	return nil, errors.New("connection lost")
+}
+
+func TestMetrics(t *testing.T) {
+	m := observe.NewMetrics()
+	d := observe.NewDatastore(phasedDatastore{}, m)
+	d.DumpCSV("sales.csv", "sales", nil, false, false, false, nil)
+	d.Exec("SELECT 1")
+
+	buf := &bytes.Buffer{}
+	if err := m.WritePrometheus(buf); err != nil {
+		t.Error("error while writing the metrics", err)
+		return
+	}
+	out := buf.String()
+	expected := []string{
+		`dbtoolkit_operations_total{operation="dump_csv",phase="",status="ok"} 1`,
+		`dbtoolkit_operation_rows_total{operation="dump_csv",phase="copy"} 3`,
+		`dbtoolkit_operation_duration_seconds_count{operation="dump_csv",phase="cleanup"} 1`,
+		`dbtoolkit_operations_total{operation="exec",phase="",status="error"} 1`,
+		`dbtoolkit_operations_in_flight{operation="dump_csv",phase="create"} 0`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e+"\n") {
+			t.Error("expected the metric", e, "in", out)
+			return
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"database/sql"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/lib/pq"
+)
+
+//tableInfoQuery selects the info of the tables from the catalog. The row estimate is 0 for the tables never analyzed
+const tableInfoQuery = `SELECT c.relname, n.nspname, GREATEST(c.reltuples, 0)::bigint, pg_total_relation_size(c.oid), COALESCE(s.n_tup_upd + s.n_tup_del, 0) = 0 ` +
+	`FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid `
+
+//ListTables returns the tables in the search path of the connection with their estimated no. of rows and size on the disk.
+//Like TableExists, only the tables that can be referred without their schema are listed
+func (p Postgres) ListTables() ([]toolkit.TableInfo, error) {
+	rows, err := p.DB.Query(tableInfoQuery + `WHERE c.relkind IN ('r', 'p') AND pg_table_is_visible(c.oid) AND n.nspname NOT IN ('pg_catalog', 'information_schema') ORDER BY c.relname`)
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+	defer rows.Close()
+	results := []toolkit.TableInfo{}
+	for rows.Next() {
+		t := toolkit.TableInfo{}
+		if err := rows.Scan(&t.Name, &t.Schema, &t.RowEstimate, &t.SizeBytes, &t.AppendOnly); err != nil {
+			return nil, TranslateError(err)
+		}
+		results = append(results, t)
+	}
+	return results, TranslateError(rows.Err())
+}
+
+//TableExists returns true if the table exists in the search path of the connection
+func (p Postgres) TableExists(tablename string) (bool, error) {
+	exists, err := tableExists(p.DB, tablename)
+	return exists, TranslateError(err)
+}
+
+//DescribeTable returns the description of the table with its columns, indexes and constraints
+func (p Postgres) DescribeTable(tablename string) (toolkit.TableDescription, error) {
+	desc, err := describeTable(p.DB, tablename)
+	return desc, TranslateError(err)
+}
+
+//describeTable returns the description of the table with its columns, indexes and constraints
+func describeTable(q queryer, tablename string) (toolkit.TableDescription, error) {
+	/*
+	 * We will get the info of the table
+	 * Then we will get the columns
+	 * Then we will get the indexes
+	 * Then we will get the constraints
+	 */
+	//getting the info of the table
+	result := toolkit.TableDescription{}
+	err := q.QueryRow(tableInfoQuery+`WHERE c.oid = to_regclass($1)`, regclassName(tablename)).
+		Scan(&result.Name, &result.Schema, &result.RowEstimate, &result.SizeBytes, &result.AppendOnly)
+	if err == sql.ErrNoRows {
+		return result, &toolkit.TableError{Op: "describe", Table: tablename, Err: toolkit.ErrTableNotFound}
+	}
+	if err != nil {
+		return result, err
+	}
+
+	//getting the columns
+	//the data type is found from the type name without the modifiers like numeric for numeric(10,2)
+	rows, err := q.Query(`SELECT a.attname, format_type(a.atttypid, a.atttypmod), format_type(a.atttypid, NULL), NOT a.attnotnull, COALESCE(pg_get_expr(d.adbin, d.adrelid), '') `+
+		`FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum `+
+		`WHERE a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum`, regclassName(tablename))
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := toolkit.ColumnInfo{}
+		typeName := ""
+		if err := rows.Scan(&c.Name, &c.DatabaseType, &typeName, &c.Nullable, &c.Default); err != nil {
+			return result, err
+		}
+		c.DataType = convertFromPostgresDataType(typeName)
+		result.Columns = append(result.Columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	//getting the indexes
+	iRows, err := q.Query(`SELECT i.relname, ix.indisunique, ix.indisprimary, am.amname, pg_get_indexdef(ix.indexrelid), `+
+		`COALESCE(obj_description(ix.indexrelid, 'pg_class'), '') = $2, `+
+		`ARRAY(SELECT a.attname FROM unnest(ix.indkey) WITH ORDINALITY k(attnum, ord) JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum ORDER BY k.ord) `+
+		`FROM pg_index ix JOIN pg_class i ON i.oid = ix.indexrelid JOIN pg_am am ON am.oid = i.relam `+
+		`WHERE ix.indrelid = to_regclass($1) ORDER BY i.relname`, regclassName(tablename), toolkit.AutoIndexComment)
+	if err != nil {
+		return result, err
+	}
+	defer iRows.Close()
+	for iRows.Next() {
+		i := toolkit.IndexInfo{}
+		cols := pq.StringArray{}
+		if err := iRows.Scan(&i.Name, &i.Unique, &i.Primary, &i.Method, &i.Definition, &i.Auto, &cols); err != nil {
+			return result, err
+		}
+		i.Columns = cols
+		result.Indexes = append(result.Indexes, i)
+	}
+	if err := iRows.Err(); err != nil {
+		return result, err
+	}
+
+	//getting the constraints
+	cRows, err := q.Query(`SELECT c.conname, c.contype, pg_get_constraintdef(c.oid), `+
+		`ARRAY(SELECT a.attname FROM unnest(c.conkey) WITH ORDINALITY k(attnum, ord) JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum ORDER BY k.ord) `+
+		`FROM pg_constraint c WHERE c.conrelid = to_regclass($1) ORDER BY c.conname`, regclassName(tablename))
+	if err != nil {
+		return result, err
+	}
+	defer cRows.Close()
+	for cRows.Next() {
+		c := toolkit.ConstraintInfo{}
+		cols := pq.StringArray{}
+		if err := cRows.Scan(&c.Name, &c.Type, &c.Definition, &cols); err != nil {
+			return result, err
+		}
+		c.Type = convertFromPostgresConstraintType(c.Type)
+		c.Columns = cols
+		result.Constraints = append(result.Constraints, c)
+	}
+	return result, cRows.Err()
+}
+
+//tableExists returns true if the table exists in the search path
+func tableExists(q queryer, tablename string) (bool, error) {
+	exists := false
+	err := q.QueryRow("SELECT to_regclass($1) IS NOT NULL", regclassName(tablename)).Scan(&exists)
+	return exists, err
+}
+
+//regclassName returns the quoted name of the table for resolving it using to_regclass
+func regclassName(tablename string) string {
+	return Dialect{}.QuoteIdentifier(tablename)
+}
+
+//convertFromPostgresConstraintType returns the constraint type for the type code of a constraint in the catalog
+func convertFromPostgresConstraintType(contype string) string {
+	switch contype {
+	case "p":
+		return toolkit.ConstraintPrimaryKey
+	case "u":
+		return toolkit.ConstraintUnique
+	case "f":
+		return toolkit.ConstraintForeignKey
+	case "c":
+		return toolkit.ConstraintCheck
+	case "x":
+		return toolkit.ConstraintExclusion
+	case "n":
+		return toolkit.ConstraintNotNull
+	default:
+		return contype
+	}
+}
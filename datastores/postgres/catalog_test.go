@@ -0,0 +1,47 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres_test
+
+import (
+	"errors"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestListAndDescribeTables(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists(`sales "listed"`)
+	defer conn.DropTableIfExists(`sales "listed"`)
+	if _, err := conn.DB.Exec(`CREATE TABLE "sales ""listed""" (id bigint PRIMARY KEY, region text)`); err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+
+	//listing the tables
+	tables, err := conn.ListTables()
+	if err != nil {
+		t.Error("error while listing the tables", err)
+		return
+	}
+	found := false
+	for _, table := range tables {
+		found = found || table.Name == `sales "listed"`
+	}
+	if !found {
+		t.Error("expected the tables to have the created table. got", tables)
+		return
+	}
+
+	//describing the tables
+	desc, err := conn.DescribeTable(`sales "listed"`)
+	if err != nil || len(desc.Columns) != 2 || desc.Columns[0].Name != "id" {
+		t.Error("expected the description to have the columns id and region. got", desc.Columns, err)
+		return
+	}
+	if _, err := conn.DescribeTable("sales_not_found"); !errors.Is(err, toolkit.ErrTableNotFound) {
+		t.Error("expected the description of a missing table to fail with", toolkit.ErrTableNotFound, "got", err)
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//AddColumn adds the column to the table. It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+//and with toolkit.ErrColumnExists if the table already has the column
+func (p Postgres) AddColumn(tablename string, column interpreter.ColumnNode) error {
+	op := "add column " + column.Name
+	d := Dialect{}
+	return p.inTableTx(op, tablename, func(tx *sql.Tx) error {
+		if err := requireTable(tx, op, tablename); err != nil {
+			return err
+		}
+		if err := requireNoColumn(tx, op, tablename, column.Name); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, d.QuoteIdentifier(tablename), d.QuoteIdentifier(column.Name), convertToPostgresDataType(column.DataType, false)))
+		return err
+	})
+}
+
+//DropColumn removes the column from the table. It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+//and with toolkit.ErrColumnNotFound if the table doesn't have the column
+func (p Postgres) DropColumn(tablename string, colName string) error {
+	op := "drop column " + colName
+	d := Dialect{}
+	return p.inTableTx(op, tablename, func(tx *sql.Tx) error {
+		if err := requireTable(tx, op, tablename); err != nil {
+			return err
+		}
+		if err := requireColumn(tx, op, tablename, colName); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, d.QuoteIdentifier(tablename), d.QuoteIdentifier(colName)))
+		return err
+	})
+}
+
+//RenameColumn renames the column in the table. It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist,
+//with toolkit.ErrColumnNotFound if the table doesn't have the column and with toolkit.ErrColumnExists if the table already has a column with the new name
+func (p Postgres) RenameColumn(tablename string, from string, to string) error {
+	op := "rename column " + from
+	d := Dialect{}
+	return p.inTableTx(op, tablename, func(tx *sql.Tx) error {
+		if err := requireTable(tx, op, tablename); err != nil {
+			return err
+		}
+		if err := requireColumn(tx, op, tablename, from); err != nil {
+			return err
+		}
+		if err := requireNoColumn(tx, op, tablename, to); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN %s TO %s`, d.QuoteIdentifier(tablename), d.QuoteIdentifier(from), d.QuoteIdentifier(to)))
+		return err
+	})
+}
+
+//ReorderColumns changes the order of the columns in the table. The given columns are moved to the front in the given order
+//and the rest of the columns follow them in their existing order.
+//As postgres can't reorder the columns in place, the table is rebuilt with its defaults, constraints and indexes in a transaction.
+//The ownership of the sequences of the serial columns, the comments and the grants of the table are carried over to the rebuilt table.
+//Tables referenced by the foreign keys or the views of other tables and tables having identity columns or column level grants can't be reordered.
+//It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+//and with toolkit.ErrColumnNotFound if the table doesn't have one of the columns
+func (p Postgres) ReorderColumns(tablename string, colNames []string) error {
+	op := "reorder columns"
+	d := Dialect{}
+	return p.inTableTx(op, tablename, func(tx *sql.Tx) error {
+		/*
+		 * We will first describe the table and find the new order of the columns
+		 * Then we will read the sequences, comments and grants of the table that have to be carried over
+		 * Then we will create a new table with the columns in the new order and copy the rows
+		 * Then we will replace the table with the new table
+		 * Then we will add back the constraints and the indexes of the table with the automatic indexes marked again
+		 * Finally we will carry over the sequences, comments and grants to the new table
+		 */
+		//describing the table and finding the new order of the columns
+		if err := requireTable(tx, op, tablename); err != nil {
+			return err
+		}
+		desc, err := describeTable(tx, tablename)
+		if err != nil {
+			return err
+		}
+		ordered, changed, err := toolkit.OrderColumns(desc.Columns, colNames)
+		if err != nil {
+			return &toolkit.TableError{Op: op, Table: tablename, Err: err}
+		}
+		if !changed {
+			return nil
+		}
+
+		//reading the sequences, comments and grants of the table
+		extras, err := readTableExtras(tx, tablename)
+		if err != nil {
+			return err
+		}
+		if extras.unsupported {
+			return &toolkit.TableError{Op: op, Table: tablename, Err: errors.New("tables with identity columns or column level grants can't be reordered")}
+		}
+		if err := extras.disown(tx); err != nil {
+			return err
+		}
+
+		//creating the new table and copying the rows
+		newTable := "_reorder_" + tablename
+		defs := make([]string, len(ordered))
+		names := make([]string, len(ordered))
+		for i, col := range ordered {
+			names[i] = col.Name
+			defs[i] = d.QuoteIdentifier(col.Name) + " " + col.DatabaseType
+			if len(col.Default) != 0 {
+				defs[i] += " DEFAULT " + col.Default
+			}
+			if !col.Nullable {
+				defs[i] += " NOT NULL"
+			}
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE %s ( %s )`, d.QuoteIdentifier(newTable), strings.Join(defs, ", "))); err != nil {
+			return err
+		}
+		qStr := fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, d.QuoteIdentifier(newTable), quoteNames(names), quoteNames(names), d.QuoteIdentifier(tablename))
+		if _, err := tx.Exec(qStr); err != nil {
+			return err
+		}
+
+		//replacing the table with the new table
+		if _, err := tx.Exec("DROP TABLE " + d.QuoteIdentifier(tablename)); err != nil {
+			return err
+		}
+		if err := renameTable(tx, newTable, tablename); err != nil {
+			return err
+		}
+
+		//adding back the constraints and the indexes
+		constraints := map[string]bool{}
+		for _, c := range desc.Constraints {
+			constraints[c.Name] = true
+			if c.Type == toolkit.ConstraintNotNull {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s %s`, d.QuoteIdentifier(tablename), d.QuoteIdentifier(c.Name), c.Definition)); err != nil {
+				return err
+			}
+		}
+		for _, i := range desc.Indexes {
+			if constraints[i.Name] {
+				//indexes of the primary key, unique and exclusion constraints are created along with them
+				continue
+			}
+			if _, err := tx.Exec(i.Definition); err != nil {
+				return err
+			}
+			if !i.Auto {
+				continue
+			}
+			if err := commentAutoIndex(tx, i.Name); err != nil {
+				return err
+			}
+		}
+
+		//carrying over the sequences, comments and grants
+		return extras.restore(tx, tablename)
+	})
+}
+
+//tableExtras has the properties of a table that are lost when the table is rebuilt
+type tableExtras struct {
+	//sequences has the owned sequences of the table mapped to the columns owning them
+	sequences map[string]string
+	//comment is the comment of the table
+	comment sql.NullString
+	//columnComments has the comments of the columns
+	columnComments map[string]string
+	//grants has the privileges granted on the table along with the grantee clause of each grant
+	grants [][2]string
+	//unsupported is set if the table has identity columns or column level grants that can't be carried over
+	unsupported bool
+}
+
+//readTableExtras reads the owned sequences, the comments and the grants of the table
+func readTableExtras(q queryer, tablename string) (tableExtras, error) {
+	/*
+	 * We will check whether the table has the identity columns or the column level grants
+	 * Then we will read the sequences owned by the columns
+	 * Then we will read the comments of the table and its columns
+	 * Finally we will read the grants of the table to roles other than the owner
+	 */
+	//checking the identity columns and the column level grants
+	extras := tableExtras{sequences: map[string]string{}, columnComments: map[string]string{}}
+	name := regclassName(tablename)
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped `+
+		`AND (attidentity <> '' OR attacl IS NOT NULL))`, name).Scan(&extras.unsupported)
+	if err != nil || extras.unsupported {
+		return extras, err
+	}
+
+	//reading the owned sequences
+	rows, err := q.Query(`SELECT d.objid::regclass::text, a.attname FROM pg_depend d JOIN pg_class s ON s.oid = d.objid `+
+		`JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid `+
+		`WHERE d.classid = 'pg_class'::regclass AND d.refobjid = to_regclass($1) AND d.deptype = 'a' AND s.relkind = 'S'`, name)
+	if err != nil {
+		return extras, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		seq, col := "", ""
+		if err := rows.Scan(&seq, &col); err != nil {
+			return extras, err
+		}
+		extras.sequences[seq] = col
+	}
+	if err := rows.Err(); err != nil {
+		return extras, err
+	}
+
+	//reading the comments
+	if err := q.QueryRow(`SELECT obj_description(to_regclass($1), 'pg_class')`, name).Scan(&extras.comment); err != nil {
+		return extras, err
+	}
+	cRows, err := q.Query(`SELECT attname, col_description(attrelid, attnum) FROM pg_attribute `+
+		`WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped AND col_description(attrelid, attnum) IS NOT NULL`, name)
+	if err != nil {
+		return extras, err
+	}
+	defer cRows.Close()
+	for cRows.Next() {
+		col, comment := "", ""
+		if err := cRows.Scan(&col, &comment); err != nil {
+			return extras, err
+		}
+		extras.columnComments[col] = comment
+	}
+	if err := cRows.Err(); err != nil {
+		return extras, err
+	}
+
+	//reading the grants
+	gRows, err := q.Query(`SELECT CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(r.rolname) END, a.privilege_type, a.is_grantable `+
+		`FROM pg_class c CROSS JOIN LATERAL aclexplode(c.relacl) a LEFT JOIN pg_roles r ON r.oid = a.grantee `+
+		`WHERE c.oid = to_regclass($1) AND a.grantee <> c.relowner`, name)
+	if err != nil {
+		return extras, err
+	}
+	defer gRows.Close()
+	for gRows.Next() {
+		grantee, privilege, grantable := "", "", false
+		if err := gRows.Scan(&grantee, &privilege, &grantable); err != nil {
+			return extras, err
+		}
+		to := "TO " + grantee
+		if grantable {
+			to += " WITH GRANT OPTION"
+		}
+		extras.grants = append(extras.grants, [2]string{privilege, to})
+	}
+	return extras, gRows.Err()
+}
+
+//disown releases the sequences from the columns of the table so that they aren't dropped along with the table
+func (t tableExtras) disown(e execer) error {
+	for seq := range t.sequences {
+		if _, err := e.Exec(`ALTER SEQUENCE ` + seq + ` OWNED BY NONE`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//restore sets the ownership of the sequences, the comments and the grants on the table
+func (t tableExtras) restore(e execer, tablename string) error {
+	d := Dialect{}
+	for seq, col := range t.sequences {
+		if _, err := e.Exec(fmt.Sprintf(`ALTER SEQUENCE %s OWNED BY %s.%s`, seq, d.QuoteIdentifier(tablename), d.QuoteIdentifier(col))); err != nil {
+			return err
+		}
+	}
+	if t.comment.Valid {
+		if _, err := e.Exec(fmt.Sprintf(`COMMENT ON TABLE %s IS %s`, d.QuoteIdentifier(tablename), quoteLiteral(t.comment.String))); err != nil {
+			return err
+		}
+	}
+	for col, comment := range t.columnComments {
+		if _, err := e.Exec(fmt.Sprintf(`COMMENT ON COLUMN %s.%s IS %s`, d.QuoteIdentifier(tablename), d.QuoteIdentifier(col), quoteLiteral(comment))); err != nil {
+			return err
+		}
+	}
+	for _, grant := range t.grants {
+		if _, err := e.Exec(fmt.Sprintf(`GRANT %s ON %s %s`, grant[0], d.QuoteIdentifier(tablename), grant[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//requireColumn returns a toolkit.TableError with toolkit.ErrColumnNotFound if the table doesn't have the column
+func requireColumn(tx *sql.Tx, op string, tablename string, colName string) error {
+	exists, err := columnExists(tx, tablename, colName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &toolkit.TableError{Op: op, Table: tablename, Err: toolkit.ErrColumnNotFound}
+	}
+	return nil
+}
+
+//requireNoColumn returns a toolkit.TableError with toolkit.ErrColumnExists if the table has the column
+func requireNoColumn(tx *sql.Tx, op string, tablename string, colName string) error {
+	exists, err := columnExists(tx, tablename, colName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &toolkit.TableError{Op: op, Table: tablename, Err: toolkit.ErrColumnExists}
+	}
+	return nil
+}
+
+//columnExists returns true if the table has the column
+func columnExists(q queryer, tablename string, colName string) (bool, error) {
+	exists := false
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = $2 AND attnum > 0 AND NOT attisdropped)`,
+		regclassName(tablename), colName).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+//Dialect renders the parts of the sql queries specific to postgres
+type Dialect struct{}
+
+//QuoteIdentifier quotes the name with double quotes escaping the double quotes in it
+func (d Dialect) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+//Placeholder returns the placeholder for the nth argument like $1
+func (d Dialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+//DataType returns the name of the postgres type for the interpreter data type
+func (d Dialect) DataType(dataType string) string {
+	return convertToPostgresDataType(dataType, false)
+}
+
+//ParseDate returns the expression parsing the text expression as a date using to_date with the date format converted to the postgres format
+func (d Dialect) ParseDate(expr string, dateFormat string) string {
+	return "to_date(" + expr + ", " + quoteLiteral(convertToPostgresFormat(dateFormat)) + ")"
+}
+
+//TruncateDate returns the expression truncating the date expression to the start of the date unit using date_trunc
+func (d Dialect) TruncateDate(expr string, unit string) string {
+	return "date_trunc(" + quoteLiteral(unit) + ", " + expr + ")::date"
+}
+
+//TruncateTable returns the TRUNCATE TABLE statement for the table
+func (d Dialect) TruncateTable(tablename string) string {
+	return "TRUNCATE TABLE " + d.QuoteIdentifier(tablename)
+}
+
+//CountDistinct returns the expression counting the distinct values of the given expression
+func (d Dialect) CountDistinct(expr string) string {
+	return "COUNT(DISTINCT " + expr + ")"
+}
+
+//LimitOffset returns the LIMIT and OFFSET clause. Zero limit or offset is left out
+func (d Dialect) LimitOffset(limit int, offset int) string {
+	clause := ""
+	if limit > 0 {
+		clause += " LIMIT " + strconv.Itoa(limit)
+	}
+	if offset > 0 {
+		clause += " OFFSET " + strconv.Itoa(offset)
+	}
+	return clause
+}
+
+//quoteLiteral quotes the value as a string literal escaping the single quotes in it
+func quoteLiteral(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+)
+
+//errorCodes has the kinds of the errors by their SQLSTATE code
+var errorCodes = map[string]error{
+	"42P01": toolkit.ErrTableNotFound,
+	"42P07": toolkit.ErrTableExists,
+	"42703": toolkit.ErrColumnNotFound,
+	"42701": toolkit.ErrColumnExists,
+	"42501": toolkit.ErrPermissionDenied,
+	"42601": toolkit.ErrSyntax,
+	"42883": toolkit.ErrSyntax,
+	"42704": toolkit.ErrSyntax,
+	"53300": toolkit.ErrTooManyConnections,
+	"40001": toolkit.ErrSerialization,
+	"40P01": toolkit.ErrDeadlock,
+	"57014": toolkit.ErrCanceled,
+	"57P01": toolkit.ErrConnection,
+	"57P02": toolkit.ErrConnection,
+	"57P03": toolkit.ErrConnection,
+}
+
+//errorClasses has the kinds of the errors by the class of their SQLSTATE code. They are used when the code isn't known
+var errorClasses = map[pq.ErrorClass]error{
+	"08": toolkit.ErrConnection,
+	"22": toolkit.ErrInvalidValue,
+	"23": toolkit.ErrConstraintViolation,
+	"28": toolkit.ErrPermissionDenied,
+	"53": toolkit.ErrInsufficientResources,
+}
+
+//TranslateError translates the error of the driver to a toolkit.DatastoreError of the kind of the error.
+//Errors already translated and the errors whose kind isn't known are returned as they are.
+//It can be used for the errors of the queries run directly on the db connection of the datastore
+func TranslateError(err error) error {
+	/*
+	 * We will first skip the errors already translated
+	 * Then we will translate the postgres errors using their SQLSTATE code
+	 * Then we will translate the connection and the cancellation errors
+	 */
+	//skipping the errors already translated
+	if err == nil {
+		return nil
+	}
+	var dErr *toolkit.DatastoreError
+	if errors.As(err, &dErr) {
+		return err
+	}
+
+	//translating the postgres errors
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return translateCode(err, pqErr.Code)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return translateCode(err, pq.ErrorCode(pgErr.Code))
+	}
+
+	//translating the connection and the cancellation errors
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return &toolkit.DatastoreError{Kind: toolkit.ErrConnection, Err: err}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &toolkit.DatastoreError{Kind: toolkit.ErrCanceled, Err: err}
+	}
+	return err
+}
+
+//translateCode translates the postgres error having the SQLSTATE code to a toolkit.DatastoreError of the kind of the code
+func translateCode(err error, code pq.ErrorCode) error {
+	kind, ok := errorCodes[string(code)]
+	if !ok {
+		kind, ok = errorClasses[code.Class()]
+	}
+	if !ok {
+		return err
+	}
+	return &toolkit.DatastoreError{Kind: kind, Code: string(code), Err: err}
+}
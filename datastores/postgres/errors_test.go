@@ -0,0 +1,48 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/postgres"
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+)
+
+func TestTranslateError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{&pq.Error{Code: "42P01"}, toolkit.ErrTableNotFound},
+		{&pq.Error{Code: "42P07"}, toolkit.ErrTableExists},
+		{&pgconn.PgError{Code: "42P01"}, toolkit.ErrTableNotFound},
+		{&pgconn.PgError{Code: "23502"}, toolkit.ErrConstraintViolation},
+		{&pq.Error{Code: "22007"}, toolkit.ErrInvalidValue},
+		{&pq.Error{Code: "23505"}, toolkit.ErrConstraintViolation},
+		{&pq.Error{Code: "42501"}, toolkit.ErrPermissionDenied},
+		{&pq.Error{Code: "40001"}, toolkit.ErrSerialization},
+		{&pq.Error{Code: "40P01"}, toolkit.ErrDeadlock},
+		{&pq.Error{Code: "53300"}, toolkit.ErrTooManyConnections},
+		{driver.ErrBadConn, toolkit.ErrConnection},
+		{context.Canceled, toolkit.ErrCanceled},
+	}
+	for _, c := range cases {
+		err := postgres.TranslateError(c.err)
+		if !errors.Is(err, c.kind) {
+			t.Error("expected", c.err, "to be translated to the kind", c.kind, "got", err)
+			return
+		}
+		if !errors.Is(err, c.err) {
+			t.Error("expected the translated error to wrap", c.err, "got", err)
+			return
+		}
+	}
+}
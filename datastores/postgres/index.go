@@ -0,0 +1,122 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+//CreateAutoIndex creates an index on the column of the table with the given access method if not exists and returns its name.
+//If the table already has a valid index with the access method on just the column, its name is returned.
+//The index is built concurrently so that the table can be read and written while building it.
+//It is marked with toolkit.AutoIndexComment so that it is reported as an automatic index while describing the table.
+//An invalid index left behind by a failed build having the name is dropped and an automatic index of another table
+//having the name, like after renaming the table, is renamed as per its table. Otherwise it returns a toolkit.TableError if the name is taken
+func (p Postgres) CreateAutoIndex(tablename string, colName string, method string) (string, error) {
+	/*
+	 * We will first validate the access method
+	 * Then we will check whether the table already has the index
+	 * Then we will free the name of the index if it is taken
+	 * Then we will build the index concurrently
+	 * Then we will mark the index as an automatic index
+	 */
+	//validating the access method
+	if method != toolkit.IndexMethodBTree && method != toolkit.IndexMethodBRIN {
+		return "", errors.New("unsupported index method " + method)
+	}
+
+	//checking whether the table already has the index
+	existing := ""
+	err := p.DB.QueryRow(`SELECT ci.relname FROM pg_index x JOIN pg_class ci ON ci.oid = x.indexrelid JOIN pg_am am ON am.oid = ci.relam `+
+		`JOIN pg_attribute a ON a.attrelid = x.indrelid AND a.attnum = x.indkey[0] WHERE x.indrelid = to_regclass($1) AND x.indisvalid `+
+		`AND x.indnatts = 1 AND x.indexprs IS NULL AND x.indpred IS NULL AND a.attname = $2 AND am.amname = $3 LIMIT 1`,
+		regclassName(tablename), colName, method).Scan(&existing)
+	if err == nil {
+		return existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", TranslateError(err)
+	}
+
+	//freeing the name of the index
+	name := toolkit.AutoIndexName(tablename, colName)
+	if err := p.freeIndexName(tablename, name); err != nil {
+		return "", err
+	}
+
+	//building the index concurrently
+	_, err = p.DB.Exec(fmt.Sprintf(`CREATE INDEX CONCURRENTLY %s ON %s USING %s (%s)`, Dialect{}.QuoteIdentifier(name), Dialect{}.QuoteIdentifier(tablename), method, Dialect{}.QuoteIdentifier(colName)))
+	if err != nil {
+		//a failed concurrent build leaves an invalid index behind
+		p.DB.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + Dialect{}.QuoteIdentifier(name))
+		return "", TranslateError(err)
+	}
+
+	//marking the index as an automatic index
+	if err := commentAutoIndex(p.DB, name); err != nil {
+		return "", TranslateError(err)
+	}
+	return name, nil
+}
+
+//freeIndexName frees the name for the automatic index of the table if it is taken by another relation.
+//An invalid index is dropped and an automatic index of another table is renamed as per its table
+func (p Postgres) freeIndexName(tablename string, name string) error {
+	/*
+	 * We will first find the relation having the name
+	 * If it is an invalid index we will drop it
+	 * If it is an automatic index of another table we will rename it as per its table
+	 * Otherwise the name is taken
+	 */
+	//finding the relation having the name
+	valid := sql.NullBool{}
+	table, column, comment := "", "", ""
+	err := p.DB.QueryRow(`SELECT x.indisvalid, COALESCE(t.relname, ''), COALESCE(a.attname, ''), COALESCE(obj_description(c.oid, 'pg_class'), '') `+
+		`FROM pg_class c LEFT JOIN pg_index x ON x.indexrelid = c.oid LEFT JOIN pg_class t ON t.oid = x.indrelid `+
+		`LEFT JOIN pg_attribute a ON a.attrelid = x.indrelid AND a.attnum = x.indkey[0] WHERE c.oid = to_regclass($1)`,
+		regclassName(name)).Scan(&valid, &table, &column, &comment)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return TranslateError(err)
+	}
+
+	//dropping the invalid index
+	if valid.Valid && !valid.Bool {
+		_, err := p.DB.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + Dialect{}.QuoteIdentifier(name))
+		return TranslateError(err)
+	}
+
+	//renaming the automatic index of another table
+	if valid.Valid && comment == toolkit.AutoIndexComment && table != tablename {
+		if newName := toolkit.AutoIndexName(table, column); newName != name {
+			_, err := p.DB.Exec("ALTER INDEX " + Dialect{}.QuoteIdentifier(name) + " RENAME TO " + Dialect{}.QuoteIdentifier(newName))
+			return TranslateError(err)
+		}
+	}
+	return &toolkit.TableError{Op: "create index " + name, Table: tablename, Err: errors.New("the index name is taken by another relation")}
+}
+
+//DropIndex drops the index if exists. The index is dropped concurrently so that the table can be read and written while dropping it
+func (p Postgres) DropIndex(indexname string) error {
+	_, err := p.DB.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + Dialect{}.QuoteIdentifier(indexname))
+	return TranslateError(err)
+}
+
+//execer can execute the queries not returning rows. Both the db connection and the transactions are execers
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+//commentAutoIndex marks the index as an automatic index
+func commentAutoIndex(e execer, indexname string) error {
+	_, err := e.Exec("COMMENT ON INDEX " + Dialect{}.QuoteIdentifier(indexname) + " IS " + quoteLiteral(toolkit.AutoIndexComment))
+	return err
+}
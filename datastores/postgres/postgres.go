@@ -8,18 +8,26 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/csv"
 	"errors"
 	"fmt"
-	"os/exec"
-	"path/filepath"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	//this package contains the postgres driver for cuttle to use it as a datastore. That is why the initalization done here
-	_ "github.com/lib/pq"
+	//this package contains the postgres driver for cuttle to use it as a datastore.
+	//Apart from the initalization, its copy in support is used for streaming the rows to the datastore
+	//its copy to support is used for streaming the exports from the datastore
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
 
 	"github.com/cuttle-ai/brain/log"
 	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/transfer"
 	"github.com/cuttle-ai/octopus/interpreter"
 )
 
@@ -27,9 +35,17 @@ import (
 type Postgres struct {
 	//DB connection instance
 	DB *sql.DB
+	//ConnString is the connection string of the database. If set, the csv exports are streamed using copy to stdout
+	//on a connection opened with it as the postgres driver of DB supports only copy from stdin
+	ConnString string
 	//DataDumpDirectory will be the name of the directory with the user name attached to it
-	//Eg. user@myserver.com:/home/user/data-directory
+	//Eg. user@myserver.com:/home/user/data-directory. It can also be a local path or an url supported by transfer.ParseStagingPath
 	DataDumpDirectory string
+	//Transport transfers the csv files to the data dump directory.
+	//If not set, the files are copied for local directories and transferred using the ssh command for the remote ones
+	Transport transfer.Transport
+	//Observer if set is reported the queries, the dumps along with their phases and the data type conversions run on the datastore
+	Observer toolkit.Observer
 }
 
 //NewPostgres returns the postgres with active connection
@@ -40,7 +56,7 @@ func NewPostgres(host, port, dbName, username, password, dataDumpDirectory strin
 	if err != nil {
 		return nil, err
 	}
-	return &Postgres{DB: db, DataDumpDirectory: dataDumpDirectory}, nil
+	return &Postgres{DB: db, DataDumpDirectory: dataDumpDirectory, ConnString: cStr}, nil
 }
 
 func convertToPostgresDataType(dataType string, maskDate bool) string {
@@ -50,7 +66,8 @@ func convertToPostgresDataType(dataType string, maskDate bool) string {
 	case interpreter.DataTypeFloat:
 		return "float"
 	case interpreter.DataTypeInt:
-		return "int"
+		//integers are stored as 64 bit so that the int64 values from the sources like parquet fit
+		return "bigint"
 	case interpreter.DataTypeDate:
 		if maskDate {
 			return "text"
@@ -65,11 +82,25 @@ func convertFromPostgresDataType(dataType string) string {
 	switch dataType {
 	case "text":
 		return interpreter.DataTypeString
-	case "float":
+	case "float", "double precision", "real", "numeric":
 		return interpreter.DataTypeFloat
-	case "int":
+	case "int", "integer", "smallint", "bigint":
 		return interpreter.DataTypeInt
-	case "date":
+	case "date", "timestamp", "timestamp without time zone", "timestamp with time zone":
+		return interpreter.DataTypeDate
+	default:
+		return interpreter.DataTypeString
+	}
+}
+
+//convertFromPostgresDatabaseType returns the interpreter data type for the database type name of a column in a result set
+func convertFromPostgresDatabaseType(typeName string) string {
+	switch typeName {
+	case "INT2", "INT4", "INT8":
+		return interpreter.DataTypeInt
+	case "FLOAT4", "FLOAT8", "NUMERIC":
+		return interpreter.DataTypeFloat
+	case "DATE", "TIMESTAMP", "TIMESTAMPTZ":
 		return interpreter.DataTypeDate
 	default:
 		return interpreter.DataTypeString
@@ -78,127 +109,1473 @@ func convertFromPostgresDataType(dataType string) string {
 
 //DumpCSV will dump the given csv file to post instance
 func (p Postgres) DumpCSV(filename string, tablename string, columns []interpreter.ColumnNode, appendData bool, createTable bool, doScp bool, logger log.Log) error {
+	_, err := p.DumpCSVWithOptions(filename, tablename, columns, toolkit.DumpOptions{
+		AppendData:  appendData,
+		CreateTable: createTable,
+		DoScp:       doScp,
+	}, logger)
+	return TranslateError(err)
+}
+
+//DumpCSVWithOptions will dump the given csv file to post instance as per the dump options.
+//Compressed files are detected by their magic bytes and are streamed to the table while being decompressed.
+//Zip archives having more than one file have to be dumped using DumpCSVArchive.
+//If streaming is set in the options, the file is streamed to the table so that the progress of the copy is reported while it runs
+func (p Postgres) DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(p.Observer, toolkit.OperationDumpCSV, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return p.dumpCSV(filename, tablename, columns, opts, logger)
+	})
+}
+
+//dumpCSV dumps the csv file to the table as per the dump options
+func (p Postgres) dumpCSV(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	/*
+	 * If the file is compressed or streaming is set we will stream it to the table
+	 * If required we will validate the rows in the file and rewrite it in the default dialect
+	 * We will copy the file to the remote
+	 * We will start a transaction for the db operation
+	 *		the progress of each phase is reported and the dump is aborted once cancelled
+	 * Then we will create the table required
+	 * If required remove the existing data
+	 * If required we will evolve the schema of the table
+	 * Then we will dump the data to the datastore
+	 *		the dates stored in the date columns are copied as text and then inserted to the table parsing them
+	 *		while merging, the data is dumped to a staging table and then merged with the table
+	 *		while replacing with a shadow table, the data is dumped to the shadow table and then swapped with the table
+	 * If required we will load the invalid rows to the reject table
+	 * Finally we will remove the file from the remote
+	 */
+	result := toolkit.DumpResult{}
+
+	//streaming the compressed file
+	//copy from the file in the data dump directory can't read compressed files
+	compression, err := toolkit.DetectCompression(filename)
+	if err != nil {
+		logger.Error("error while detecting the compression of the csv file", filename)
+		return result, TranslateError(err)
+	}
+	if compression != toolkit.CompressionNone {
+		logger.Info("streaming the", compression.String(), "compressed csv file", filename, "to the table", tablename)
+		return p.dumpCompressedCSV(filename, "", tablename, columns, opts, logger)
+	}
+
+	//streaming the file
+	//copy from the file in the data dump directory can't report its progress until it completes
+	if opts.Stream {
+		logger.Info("streaming the csv file", filename, "to the table", tablename)
+		return p.streamCSV(filename, tablename, columns, opts, logger)
+	}
+
+	//validating the rows in the file
+	//files written in a dialect other than the default one are rewritten so that they can be copied as it is
+	rejectFilename := ""
+	if opts.Validation != toolkit.ValidationNone || !opts.Dialect.IsDefault() {
+		logger.Info("validating the rows in the csv file", filename)
+		validated, err := toolkit.ValidateCSV(filename, columns, opts)
+		result = validated.Result
+		if err != nil {
+			logger.Error("error while validating the rows in the csv file", filename)
+			return result, TranslateError(err)
+		}
+		defer validated.Remove()
+		filename = validated.Filename
+		rejectFilename = validated.RejectFilename
+		logger.Info("validated the rows in the csv file. no. of invalid rows:-", result.RowsRejected)
+	}
+
+	//copying the file to the remote
+	tracker := toolkit.NewProgressTracker(opts)
+	ctx := tracker.Context()
+	staging, err := transfer.ParseStagingPath(p.DataDumpDirectory)
+	if err != nil {
+		logger.Error("error while parsing the data dump directory", p.DataDumpDirectory)
+		return result, TranslateError(err)
+	}
+	transport := p.transport(staging)
+	remoteFileName := staging.Join(tablename + ".csv")
+	src, err := os.Open(filename)
+	if err != nil {
+		logger.Error("error while opening the file for dumping csv to the datastore", filename)
+		return result, TranslateError(err)
+	}
+	defer src.Close()
+	if info, err := src.Stat(); err == nil {
+		tracker.SetTotal(info.Size(), 0)
+	}
+	if err := tracker.Phase(toolkit.PhaseTransfer); err != nil {
+		return result, TranslateError(err)
+	}
+	logger.Info("copying the file to remote postgres server", staging.String())
+	err = transport.Put(ctx, tracker.Reader(src), remoteFileName)
+	if err != nil {
+		logger.Error("error copying the file for dumping csv to the datastore", filename, "to", staging.String())
+		return result, TranslateError(err)
+	}
+	tracker.Done()
+	//the file in the data dump directory is removed once the dump is over
+	defer func() {
+		logger.Info("removing the data file from the remote server")
+		if err := transport.Remove(remoteFileName); err != nil {
+			logger.Error("error removing the file from the server after dumping csv to the datastore", remoteFileName, err)
+		}
+	}()
+
+	//starting the db transaction
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error while creating the db transaction for dumping csv to the datastore")
+		return result, TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	//we will create the table
+	if err := tracker.Phase(toolkit.PhaseCreate); err != nil {
+		return result, TranslateError(err)
+	}
+	err = prepareTable(tx, tablename, columns, "", opts, logger)
+	if err != nil {
+		return result, TranslateError(err)
+	}
+
+	//evolving the schema of the table for the appended data
+	result.SchemaChanges, err = evolveTable(tx, tablename, columns, opts, logger)
+	if err != nil {
+		return result, TranslateError(err)
+	}
+
+	//while merging the data is copied to a staging table first
+	//while replacing with a shadow table the data is copied to the shadow table
+	copyTable := tablename
+	if len(opts.MergeKeys) != 0 {
+		copyTable, err = createStagingTable(tx, tablename, columns, "", opts, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+	if shadowReplace(opts) {
+		copyTable, err = createShadowTable(tx, tablename, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+
+	//the dates to be stored in the date columns are copied as text and parsed with their date format
+	loadTable := copyTable
+	dates, err := dateColumns(tx, copyTable, columns)
+	if err != nil {
+		logger.Error("error while getting the date columns of the table", copyTable)
+		return result, TranslateError(err)
+	}
+	if len(dates) != 0 {
+		loadTable, err = createDatesTable(tx, copyTable, columnNames(columns, ""), dates, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+
+	//now we will dump the data to the datastore
+	if err := tracker.Phase(toolkit.PhaseCopy); err != nil {
+		return result, TranslateError(err)
+	}
+	logger.Info("copying the data from the csv to the table", remoteFileName, tablename)
+	qStr := fmt.Sprintf(`COPY "%s" %s FROM %s DELIMITER ',' CSV HEADER;`, loadTable, columnList(columns), quoteLiteral(remoteFileName))
+	res, err := tx.ExecContext(ctx, qStr)
+	if err != nil {
+		logger.Error("error while dumping to the table", tablename, "from csv", remoteFileName)
+		return result, TranslateError(err)
+	}
+	ef, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("error while getting the number of rows affected while dumping the data to the datastore")
+		return result, TranslateError(err)
+	}
+	if len(dates) != 0 {
+		if err := insertParsedDates(tx, loadTable, copyTable, columnNames(columns, ""), dates, logger); err != nil {
+			return result, TranslateError(err)
+		}
+	}
+	result.RowsLoaded = ef
+	tracker.AddBytes(tracker.Progress().TotalBytes)
+	tracker.AddRows(ef)
+	tracker.Done()
+
+	//merging the staging table or swapping the shadow table with the table
+	if err := tracker.Phase(toolkit.PhaseCleanup); err != nil {
+		return result, TranslateError(err)
+	}
+	if len(opts.MergeKeys) != 0 {
+		err = mergeStagingTable(tx, copyTable, tablename, columnNames(columns, ""), opts, &result, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+	if shadowReplace(opts) {
+		err = swapShadowTable(tx, copyTable, tablename, opts.ExpectedRows, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+
+	//loading the invalid rows to the reject table
+	if len(rejectFilename) != 0 {
+		rejectTable := rejectTableName(tablename, opts)
+		logger.Info("loading the invalid rows to the reject table", rejectTable)
+		err = loadRejectedRows(tx, rejectFilename, rejectTable, columns, opts.AppendData)
+		if err != nil {
+			logger.Error("error while loading the invalid rows to the reject table", rejectTable)
+			return result, TranslateError(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logger.Error("error while commiting the changes")
+		return result, TranslateError(err)
+	}
+	tracker.Done()
+
+	logger.Info("successfully dumped the csv to the table", filename, tablename, "copied no. of rows:-", ef)
+	return result, nil
+}
+
+//transport returns the transport for transferring the files to the staging directory.
+//If the transport of the postgres is not set, the files are copied for the local directories
+//and transferred using the ssh command for the remote ones
+func (p Postgres) transport(staging transfer.StagingPath) transfer.Transport {
+	if p.Transport != nil {
+		return p.Transport
+	}
+	if staging.IsLocal() {
+		return transfer.Local{}
+	}
+	return transfer.Command{Staging: staging}
+}
+
+//DumpCSVArchive will dump the csv files in the given zip archive to the postgres instance as per the dump options.
+//Each file in the archive is dumped to its own table named using toolkit.ChildTableName with the name of the file
+//without its extension. If the file is not a zip archive, it is dumped to the table using DumpCSVWithOptions.
+//If no columns are given, the columns in the header of each file are used.
+//The files are decompressed while being streamed to the tables. It returns the results of the dump by the table name
+func (p Postgres) DumpCSVArchive(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	return toolkit.ObserveDumps(p.Observer, toolkit.OperationDumpCSVArchive, tablename, opts, func(opts toolkit.DumpOptions) (map[string]toolkit.DumpResult, error) {
+		return p.dumpCSVArchive(filename, tablename, columns, opts, logger)
+	})
+}
+
+//dumpCSVArchive dumps the csv files in the archive to their tables as per the dump options
+func (p Postgres) dumpCSVArchive(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	/*
+	 * We will find the files in the archive
+	 * If it is not an archive, we will dump the file as it is
+	 * Then we will dump each file to its table
+	 */
+	//finding the files in the archive
+	results := map[string]toolkit.DumpResult{}
+	members, err := toolkit.ArchiveMembers(filename)
+	if err != nil {
+		logger.Error("error while reading the files in the archive", filename)
+		return results, TranslateError(err)
+	}
+
+	//dumping the file which is not an archive
+	if members == nil {
+		result, err := p.dumpCSV(filename, tablename, columns, opts, logger)
+		results[tablename] = result
+		return results, TranslateError(err)
+	}
+
+	//dumping the files in the archive
+	for _, member := range members {
+		table := toolkit.ChildTableName(tablename, toolkit.ArchiveMemberName(member))
+		logger.Info("dumping the file", member, "in the archive to the table", table)
+		result, err := p.dumpCompressedCSV(filename, member, table, columns, opts, logger)
+		results[table] = result
+		if err != nil {
+			logger.Error("error while dumping the file", member, "in the archive to the table", table)
+			return results, TranslateError(err)
+		}
+	}
+	return results, nil
+}
+
+//streamCSV streams the rows in the csv file to the table reporting the bytes read and the rows copied
+func (p Postgres) streamCSV(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		logger.Error("error while opening the csv file", filename)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	defer f.Close()
+	tracker := toolkit.NewProgressTracker(opts)
+	if info, err := f.Stat(); err == nil {
+		tracker.SetTotal(info.Size(), 0)
+	}
+	rows, err := toolkit.NewCSVRowReader(tracker.Reader(f), opts.Dialect)
+	if err != nil {
+		logger.Error("error while reading the header of the csv file", filename)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	if len(columns) == 0 {
+		columns = rows.Columns()
+	}
+	return p.dumpRows(rows, tablename, columns, "", opts, tracker, logger)
+}
+
+//dumpCompressedCSV streams the csv in the compressed file to the table while decompressing it.
+//For zip archives, the member is the file in the archive to be dumped
+func (p Postgres) dumpCompressedCSV(filename string, member string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	r, err := toolkit.OpenDecompressed(filename, member)
+	if err != nil {
+		logger.Error("error while opening the compressed csv file", filename, member)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	defer r.Close()
+	tracker := toolkit.NewProgressTracker(opts)
+	rows, err := toolkit.NewCSVRowReader(tracker.Reader(r), opts.Dialect)
+	if err != nil {
+		logger.Error("error while reading the header of the compressed csv file", filename, member)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	if len(columns) == 0 {
+		columns = rows.Columns()
+	}
+	return p.dumpRows(rows, tablename, columns, "", opts, tracker, logger)
+}
+
+//DumpRows will dump the rows read from the reader to the postgres instance as per the dump options.
+//The rows are streamed to the table without staging them in the data dump directory
+func (p Postgres) DumpRows(rows toolkit.RowReader, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(p.Observer, toolkit.OperationDumpRows, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return p.dumpRows(rows, tablename, columns, "", opts, toolkit.NewProgressTracker(opts), logger)
+	})
+}
+
+//DumpJSONL will dump the given json lines file to the postgres instance as per the dump options.
+//If the overflow column is set in the options, the structure not mapped to the columns is stored in it as jsonb
+func (p Postgres) DumpJSONL(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(p.Observer, toolkit.OperationDumpJSONL, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return p.dumpJSONL(filename, tablename, columns, opts, logger)
+	})
+}
+
+//dumpJSONL dumps the json lines file to the table as per the dump options
+func (p Postgres) dumpJSONL(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		logger.Error("error while opening the json lines file", filename)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	defer f.Close()
+	tracker := toolkit.NewProgressTracker(opts)
+	if info, err := f.Stat(); err == nil {
+		tracker.SetTotal(info.Size(), 0)
+	}
+	rows := toolkit.NewJSONLReader(tracker.Reader(f), columns, len(opts.OverflowColumn) != 0)
+	return p.dumpRows(rows, tablename, columns, opts.OverflowColumn, opts, tracker, logger)
+}
+
+//DumpParquet will dump the given parquet file to the postgres instance as per the dump options.
+//If no columns are given, the columns in the parquet file are used. The file is read one row group at a time
+func (p Postgres) DumpParquet(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(p.Observer, toolkit.OperationDumpParquet, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return p.dumpParquet(filename, tablename, columns, opts, logger)
+	})
+}
+
+//dumpParquet dumps the parquet file to the table as per the dump options
+func (p Postgres) dumpParquet(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	rows, err := toolkit.NewParquetReader(filename)
+	if err != nil {
+		logger.Error("error while opening the parquet file", filename)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	defer rows.Close()
+	if len(columns) == 0 {
+		columns = rows.Columns()
+	}
+	rows.Select(columns)
+	logger.Info("dumping the parquet file having no. of rows:-", rows.NumRows(), "to the table", tablename)
+	tracker := toolkit.NewProgressTracker(opts)
+	tracker.SetTotal(0, rows.NumRows())
+	return p.dumpRows(rows, tablename, columns, "", opts, tracker, logger)
+}
+
+//DumpExcel will dump the sheets in the given excel workbook to the postgres instance as per the dump options.
+//If all the sheets are to be read, each sheet is dumped to its own table named using toolkit.ChildTableName.
+//If no columns are given, the columns in the header of the sheet are used.
+//It returns the results of the dump by the table name
+func (p Postgres) DumpExcel(filename string, tablename string, columns []interpreter.ColumnNode, excel toolkit.ExcelOptions, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	return toolkit.ObserveDumps(p.Observer, toolkit.OperationDumpExcel, tablename, opts, func(opts toolkit.DumpOptions) (map[string]toolkit.DumpResult, error) {
+		return p.dumpExcel(filename, tablename, columns, excel, opts, logger)
+	})
+}
+
+//dumpExcel dumps the sheets of the excel workbook to their tables as per the dump options
+func (p Postgres) dumpExcel(filename string, tablename string, columns []interpreter.ColumnNode, excel toolkit.ExcelOptions, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	/*
+	 * We will find the sheets to be dumped
+	 * Then we will dump each sheet to its table
+	 */
+	//finding the sheets to be dumped
+	results := map[string]toolkit.DumpResult{}
+	sheets := []string{excel.Sheet}
+	tables := []string{tablename}
+	if excel.AllSheets {
+		names, err := toolkit.ExcelSheets(filename)
+		if err != nil {
+			logger.Error("error while reading the sheets in the workbook", filename)
+			return results, TranslateError(err)
+		}
+		sheets = names
+		tables = make([]string, len(names))
+		for i, name := range names {
+			tables[i] = toolkit.ChildTableName(tablename, name)
+		}
+	}
+
+	//dumping the sheets
+	for i, sheet := range sheets {
+		table := tables[i]
+		sheetOpts := excel
+		sheetOpts.Sheet = sheet
+		rows, err := toolkit.NewExcelReader(filename, sheetOpts)
+		if err != nil {
+			logger.Error("error while opening the sheet", sheet, "in the workbook", filename)
+			return results, TranslateError(err)
+		}
+		cols := columns
+		if len(cols) == 0 {
+			cols = rows.Columns()
+		}
+		rows.Select(cols)
+		logger.Info("dumping the sheet", sheet, "to the table", table)
+		result, err := p.dumpRows(rows, table, cols, "", opts, toolkit.NewProgressTracker(opts), logger)
+		rows.Close()
+		results[table] = result
+		if err != nil {
+			logger.Error("error while dumping the sheet", sheet, "to the table", table)
+			return results, TranslateError(err)
+		}
+	}
+	return results, nil
+}
+
+//ExportCSV will export the given table to w as csv as per the export options.
+//The rows are streamed from the table to the writer so that the memory used doesn't grow with the size of the table
+func (p Postgres) ExportCSV(w io.Writer, tablename string, opts toolkit.ExportOptions) error {
+	return p.ExportQueryCSV(w, opts, "SELECT * FROM "+Dialect{}.QuoteIdentifier(tablename))
+}
+
+//ExportQueryCSV will export the result of the given query to w as csv as per the export options.
+//The query is run in a read only transaction so that it can't modify the data.
+//If the datastore has the connection string, the result is streamed from the server using copy to stdout.
+//Copy doesn't take arguments, so the queries with arguments and the dialects copy can't write
+//are streamed by reading the rows of the query. Either way the memory used is independent of the no. of rows.
+//The values of the date columns are written in toolkit.ExportDateFormat
+func (p Postgres) ExportQueryCSV(w io.Writer, opts toolkit.ExportOptions, query string, args ...interface{}) error {
+	/*
+	 * We will copy the result to stdout if possible
+	 * Else we will start a read only transaction
+	 * Then we will run the query
+	 * Then we will write the header
+	 * Then we will write the rows
+	 */
+	//copying the result to stdout if possible
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewLogger()
+	}
+	cw, err := toolkit.NewCSVWriter(w, opts.Dialect)
+	if err != nil {
+		logger.Error("error while creating the csv writer for exporting the query result")
+		return TranslateError(err)
+	}
+	if len(args) == 0 && len(p.ConnString) != 0 && copyDialect(opts.Dialect) {
+		return p.copyQueryCSV(ctx, w, cw, opts, query, logger)
+	}
+
+	//starting the read only transaction
+	tx, err := p.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		logger.Error("error while creating the read only transaction for exporting the query result")
+		return TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	//running the query
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("error while running the query for exporting its result", query)
+		return TranslateError(err)
+	}
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		logger.Error("error while getting the column types of the query result for exporting it")
+		return TranslateError(err)
+	}
+
+	//writing the header
+	if !opts.Dialect.NoHeader {
+		header := opts.Header
+		if len(header) == 0 {
+			header = make([]string, len(colTypes))
+			for i, c := range colTypes {
+				header[i] = c.Name()
+			}
+		}
+		if err := cw.WriteStrings(header); err != nil {
+			logger.Error("error while writing the header of the exported csv")
+			return TranslateError(err)
+		}
+	}
+
+	//writing the rows
+	vals := make([]interface{}, len(colTypes))
+	ptrs := make([]interface{}, len(colTypes))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			logger.Error("error while reading a row of the query result for exporting it")
+			return TranslateError(err)
+		}
+		for i, c := range colTypes {
+			vals[i] = toolkit.ExportValue(vals[i], c.DatabaseTypeName() == "DATE")
+		}
+		if err := cw.Write(vals); err != nil {
+			logger.Error("error while writing a row of the exported csv")
+			return TranslateError(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error while reading the rows of the query result for exporting it")
+		return TranslateError(err)
+	}
+	if err := cw.Flush(); err != nil {
+		logger.Error("error while flushing the exported csv")
+		return TranslateError(err)
+	}
+	return nil
+}
+
+//copyDialect returns true if copy to stdout can write the csv in the dialect.
+//Copy takes only single byte delimiter, quote and escape characters
+func copyDialect(d toolkit.CSVDialect) bool {
+	d = d.WithDefaults()
+	for _, ru := range []rune{d.Delimiter, d.Quote, d.Escape} {
+		if ru >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+//copyQueryCSV streams the result of the query to w as csv using copy to stdout.
+//The copy is run in a read only transaction on a connection opened using the connection string of the datastore.
+//The header if given in the options is written using the csv writer before the copy
+func (p Postgres) copyQueryCSV(ctx context.Context, w io.Writer, cw *toolkit.CSVWriter, opts toolkit.ExportOptions, query string, logger log.Log) error {
+	/*
+	 * We will open the connection
+	 * Then we will start a read only transaction
+	 * Then we will write the header if given
+	 * Then we will copy the result to the writer
+	 */
+	//opening the connection
+	conn, err := pgconn.Connect(ctx, p.ConnString)
+	if err != nil {
+		logger.Error("error while opening the connection for copying the query result")
+		return TranslateError(err)
+	}
+	defer conn.Close(context.Background())
+
+	//starting the read only transaction
+	_, err = conn.Exec(ctx, "BEGIN READ ONLY; SET LOCAL DateStyle = 'ISO, YMD'; SET LOCAL client_encoding = 'UTF8'").ReadAll()
+	if err != nil {
+		logger.Error("error while creating the read only transaction for copying the query result")
+		return TranslateError(err)
+	}
+
+	//writing the header if given
+	d := opts.Dialect.WithDefaults()
+	header := !d.NoHeader
+	if header && len(opts.Header) != 0 {
+		header = false
+		if err := cw.WriteStrings(opts.Header); err != nil {
+			logger.Error("error while writing the header of the exported csv")
+			return TranslateError(err)
+		}
+		if err := cw.Flush(); err != nil {
+			logger.Error("error while flushing the header of the exported csv")
+			return TranslateError(err)
+		}
+	}
+
+	//copying the result to the writer
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	copyQuery := "COPY (" + query + ") TO STDOUT WITH (FORMAT csv, HEADER " + strconv.FormatBool(header) +
+		", DELIMITER " + quoteLiteral(string(d.Delimiter)) + ", QUOTE " + quoteLiteral(string(d.Quote)) +
+		", ESCAPE " + quoteLiteral(string(d.Escape)) + ", NULL " + quoteLiteral(d.NullString) + ")"
+	if _, err := conn.CopyTo(ctx, w, copyQuery); err != nil {
+		logger.Error("error while copying the query result for exporting it", query)
+		return TranslateError(err)
+	}
+	_, err = conn.Exec(ctx, "COMMIT").ReadAll()
+	return TranslateError(err)
+}
+
+//ExportParquet will export the given table to w in the parquet format.
+//The table is read in a read only transaction. The rows of the table are streamed to the writer and flushed as row groups
+func (p Postgres) ExportParquet(w io.Writer, tablename string) error {
+	/*
+	 * We will start a read only transaction
+	 * Then we will query the table
+	 * Then we will create the parquet writer with the columns of the table
+	 * Then we will write the rows to the writer
+	 */
+	//starting the read only transaction
+	tx, err := p.DB.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	//querying the table
+	rows, err := tx.Query("SELECT * FROM " + Dialect{}.QuoteIdentifier(tablename))
+	if err != nil {
+		return TranslateError(err)
+	}
+	defer rows.Close()
+
+	//creating the parquet writer
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return TranslateError(err)
+	}
+	columns := make([]toolkit.Column, len(colTypes))
+	for i, c := range colTypes {
+		columns[i] = toolkit.Column{Name: c.Name(), DataType: convertFromPostgresDatabaseType(c.DatabaseTypeName())}
+	}
+	pw, err := toolkit.NewParquetWriter(w, columns)
+	if err != nil {
+		return TranslateError(err)
+	}
+
+	//writing the rows
+	for rows.Next() {
+		vals := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return TranslateError(err)
+		}
+		if err := pw.Write(vals); err != nil {
+			return TranslateError(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return TranslateError(err)
+	}
+	return pw.Close()
+}
+
+//dumpRows streams the rows from the reader to the table as per the dump options.
+//The json column if given has the values after the columns in the rows. The progress is reported using the tracker
+func (p Postgres) dumpRows(rows toolkit.RowReader, tablename string, columns []interpreter.ColumnNode, jsonColumn string, opts toolkit.DumpOptions, tracker *toolkit.ProgressTracker, logger log.Log) (toolkit.DumpResult, error) {
+	/*
+	 * We will start a transaction for the db operation
+	 * Then we will create the table required
+	 * If required remove the existing data
+	 * If required we will evolve the schema of the table
+	 * Then we will stream the rows to the table
+	 *		the dates stored in the date columns are streamed as text and then inserted to the table parsing them
+	 *		while merging, the rows are streamed to a staging table and then merged with the table
+	 *		while replacing with a shadow table, the rows are streamed to the shadow table and then swapped with the table
+	 * If required we will load the invalid rows to the reject table
+	 * Then we will commit the changes
+	 *		the progress of each phase is reported and the dump is aborted once cancelled
+	 */
+	//starting the db transaction
+	tx, err := p.DB.BeginTx(tracker.Context(), nil)
+	if err != nil {
+		logger.Error("error while creating the db transaction for dumping rows to the datastore")
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	//creating the table
+	if err := tracker.Phase(toolkit.PhaseCreate); err != nil {
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	err = prepareTable(tx, tablename, columns, jsonColumn, opts, logger)
+	if err != nil {
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+
+	//evolving the schema of the table for the appended data
+	changes, err := evolveTable(tx, tablename, columns, opts, logger)
+	if err != nil {
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+
+	//while merging the rows are streamed to a staging table first
+	//while replacing with a shadow table the rows are streamed to the shadow table
+	colNames := columnNames(columns, jsonColumn)
+	copyTable := tablename
+	if len(opts.MergeKeys) != 0 {
+		copyTable, err = createStagingTable(tx, tablename, columns, jsonColumn, opts, logger)
+		if err != nil {
+			return toolkit.DumpResult{}, TranslateError(err)
+		}
+	}
+	if shadowReplace(opts) {
+		copyTable, err = createShadowTable(tx, tablename, logger)
+		if err != nil {
+			return toolkit.DumpResult{}, TranslateError(err)
+		}
+	}
+
+	//the dates to be stored in the date columns are copied as text and parsed with their date format
+	loadTable := copyTable
+	dates, err := dateColumns(tx, copyTable, columns)
+	if err != nil {
+		logger.Error("error while getting the date columns of the table", copyTable)
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	if len(dates) != 0 {
+		loadTable, err = createDatesTable(tx, copyTable, colNames, dates, logger)
+		if err != nil {
+			return toolkit.DumpResult{}, TranslateError(err)
+		}
+		rows = newDateRowReader(rows, columns, dates)
+	}
+
+	//streaming the rows to the table
+	if err := tracker.Phase(toolkit.PhaseCopy); err != nil {
+		return toolkit.DumpResult{}, TranslateError(err)
+	}
+	logger.Info("copying the rows to the table", copyTable)
+	result, rejectFilename, err := copyRows(tx, loadTable, colNames, columns, rows, opts, tracker)
+	result.SchemaChanges = changes
+	if len(rejectFilename) != 0 {
+		defer os.Remove(rejectFilename)
+	}
+	if err != nil {
+		logger.Error("error while copying the rows to the table", copyTable)
+		return result, TranslateError(err)
+	}
+	if len(dates) != 0 {
+		if err := insertParsedDates(tx, loadTable, copyTable, colNames, dates, logger); err != nil {
+			return result, TranslateError(err)
+		}
+	}
+	tracker.Done()
+
+	//merging the staging table or swapping the shadow table with the table
+	if err := tracker.Phase(toolkit.PhaseCleanup); err != nil {
+		return result, TranslateError(err)
+	}
+	if len(opts.MergeKeys) != 0 {
+		err = mergeStagingTable(tx, copyTable, tablename, colNames, opts, &result, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+	if shadowReplace(opts) {
+		err = swapShadowTable(tx, copyTable, tablename, opts.ExpectedRows, logger)
+		if err != nil {
+			return result, TranslateError(err)
+		}
+	}
+
+	//loading the invalid rows to the reject table
+	if len(rejectFilename) != 0 && result.RowsRejected > 0 {
+		rejectTable := rejectTableName(tablename, opts)
+		logger.Info("loading the invalid rows to the reject table", rejectTable)
+		err = loadRejectedRows(tx, rejectFilename, rejectTable, columns, opts.AppendData)
+		if err != nil {
+			logger.Error("error while loading the invalid rows to the reject table", rejectTable)
+			return result, TranslateError(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logger.Error("error while commiting the changes")
+		return result, TranslateError(err)
+	}
+	tracker.Done()
+	logger.Info("successfully dumped the rows to the table", tablename, "copied no. of rows:-", result.RowsLoaded)
+	return result, nil
+}
+
+//prepareTable creates the table for dumping the data if required else truncates it when the data is not appended.
+//The json column if given is created as jsonb after the columns
+func prepareTable(tx *sql.Tx, tablename string, columns []interpreter.ColumnNode, jsonColumn string, opts toolkit.DumpOptions, logger log.Log) error {
+	//we will first build the query string to create the table
+	logger.Info("building the table to dump the data", tablename)
+	var strB strings.Builder
+	strB.WriteString("CREATE TABLE ")
+	strB.WriteString(`"` + tablename + `"`)
+	strB.WriteString("( ")
+	for k, col := range columns {
+		if k > 0 {
+			strB.WriteString(", ")
+		}
+		strB.WriteString("\"" + col.Name + "\" " + convertToPostgresDataType(col.DataType, true))
+	}
+	if len(jsonColumn) != 0 {
+		strB.WriteString(", \"" + jsonColumn + "\" jsonb")
+	}
+	strB.WriteString(" )")
+
+	//now executing the built query
+	if opts.CreateTable {
+		_, err := tx.Exec(strB.String())
+		if err != nil {
+			logger.Error("error while creating the table", tablename, "for dumping the data to the datastore")
+			return err
+		}
+	}
+
+	if !opts.AppendData && !opts.CreateTable && len(opts.MergeKeys) == 0 && !opts.ShadowReplace {
+		_, err := tx.Exec(Dialect{}.TruncateTable(tablename))
+		if err != nil {
+			logger.Error("error while truncating the table", tablename, "for replacing the data in the datastore")
+			return err
+		}
+	}
+	return nil
+}
+
+//evolveTable evolves the schema of the existing table to store the appended data with the given columns.
+//Columns not present in the table are added and the data types of the columns are widened if required.
+//The schema is evolved only if the data is appended to an existing table with the schema evolution enabled in the dump options
+func evolveTable(tx *sql.Tx, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) ([]toolkit.SchemaChange, error) {
+	if !opts.EvolveSchema || !opts.AppendData || opts.CreateTable {
+		return nil, nil
+	}
+	logger.Info("evolving the schema of the table", tablename, "for appending the data")
+	existing, err := columnTypes(tx, tablename)
+	if err != nil {
+		logger.Error("error while getting the columns of the table", tablename, "for evolving its schema")
+		return nil, err
+	}
+	changes, err := toolkit.PlanSchemaEvolution(existing, columns)
+	if err != nil {
+		logger.Error("error while planning the schema evolution of the table", tablename)
+		return nil, err
+	}
+	for _, c := range changes {
+		qStr := ""
+		switch c.Kind {
+		case toolkit.SchemaChangeAddColumn:
+			qStr = fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`, tablename, c.Column, convertToPostgresDataType(c.To, true))
+		case toolkit.SchemaChangeWidenColumn:
+			pType := convertToPostgresDataType(c.To, false)
+			qStr = fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" TYPE %s USING "%s"::%s`, tablename, c.Column, pType, c.Column, pType)
+		default:
+			continue
+		}
+		if _, err := tx.Exec(qStr); err != nil {
+			logger.Error("error while evolving the schema of the table", tablename, c.String())
+			return nil, err
+		}
+		logger.Info("evolved the schema of the table", tablename, c.String())
+	}
+	return changes, nil
+}
+
+//createStagingTable creates a temporary staging table having the given columns and the json column of the table with the same data types.
+//The data to be merged with the table is loaded to the staging table. It is dropped when the transaction ends
+func createStagingTable(tx *sql.Tx, tablename string, columns []interpreter.ColumnNode, jsonColumn string, opts toolkit.DumpOptions, logger log.Log) (string, error) {
+	if err := toolkit.ValidateMergeKeys(opts.MergeKeys, columns); err != nil {
+		logger.Error("invalid merge keys for merging the data to the table", tablename)
+		return "", err
+	}
+	stagingTable := "_staging_" + tablename
+	logger.Info("creating the staging table", stagingTable, "for merging the data to the table", tablename)
+	qStr := fmt.Sprintf(`CREATE TEMP TABLE "%s" ON COMMIT DROP AS SELECT %s FROM "%s" WITH NO DATA`, stagingTable, quoteNames(columnNames(columns, jsonColumn)), tablename)
+	if _, err := tx.Exec(qStr); err != nil {
+		logger.Error("error while creating the staging table", stagingTable, "for merging the data to the table", tablename)
+		return "", err
+	}
+	if err := disallowNullKeys(tx, stagingTable, opts.MergeKeys, logger); err != nil {
+		return "", err
+	}
+	return stagingTable, nil
+}
+
+//disallowNullKeys disallows the nulls in the merge keys of the table to which the data to be merged is loaded.
+//Rows with null keys can't be merged as they never conflict, so loading them fails
+func disallowNullKeys(tx *sql.Tx, tablename string, keys []string, logger log.Log) error {
+	for _, k := range keys {
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" SET NOT NULL`, tablename, k)); err != nil {
+			logger.Error("error while disallowing the nulls in the merge key", k, "of the table", tablename)
+			return err
+		}
+	}
+	return nil
+}
+
+//dateColumns returns the date formats of the columns having the dates that are stored as dates in the table.
+//Copy parses only the iso dates, so the values of these columns are copied as text and parsed using their date format
+func dateColumns(tx *sql.Tx, tablename string, columns []interpreter.ColumnNode) (map[string]string, error) {
+	existing, err := columnTypes(tx, tablename)
+	if err != nil {
+		return nil, err
+	}
+	stored := map[string]bool{}
+	for _, col := range existing {
+		stored[col.Name] = col.DataType == interpreter.DataTypeDate
+	}
+	formats := map[string]string{}
+	for _, col := range columns {
+		if col.DataType == interpreter.DataTypeDate && len(col.DateFormat) != 0 && stored[col.Name] {
+			formats[col.Name] = col.DateFormat
+		}
+	}
+	return formats, nil
+}
+
+//createDatesTable creates a temporary table having the given columns of the table with the date columns as text.
+//The rows are copied to it and then inserted to the table parsing the dates using insertParsedDates. It is dropped when the transaction ends
+func createDatesTable(tx *sql.Tx, tablename string, colNames []string, dates map[string]string, logger log.Log) (string, error) {
+	datesTable := "_dates_" + tablename
+	logger.Info("creating the table", datesTable, "for parsing the dates copied to the table", tablename)
+	cols := make([]string, len(colNames))
+	for i, name := range colNames {
+		cols[i] = `"` + name + `"`
+		if _, ok := dates[name]; ok {
+			cols[i] += `::text AS "` + name + `"`
+		}
+	}
+	qStr := fmt.Sprintf(`CREATE TEMP TABLE "%s" ON COMMIT DROP AS SELECT %s FROM "%s" WITH NO DATA`, datesTable, strings.Join(cols, ", "), tablename)
+	if _, err := tx.Exec(qStr); err != nil {
+		logger.Error("error while creating the table", datesTable, "for parsing the dates copied to the table", tablename)
+		return "", err
+	}
+	return datesTable, nil
+}
+
+//dateRowReader formats the time values read for the date columns in their date format so that they can be parsed like the text values
+type dateRowReader struct {
+	toolkit.RowReader
+	formats []string
+}
+
+//newDateRowReader returns a reader formatting the time values of the columns having the date formats
+func newDateRowReader(rows toolkit.RowReader, columns []interpreter.ColumnNode, dates map[string]string) *dateRowReader {
+	formats := make([]string, len(columns))
+	for i, col := range columns {
+		formats[i] = dates[col.Name]
+	}
+	return &dateRowReader{RowReader: rows, formats: formats}
+}
+
+//Raw returns the text of the last read row in the source if the source is a raw row reader
+func (d *dateRowReader) Raw() string {
+	return toolkit.RawRow(d.RowReader)
+}
+
+//Read reads the next row formatting the time values of the date columns
+func (d *dateRowReader) Read() ([]interface{}, error) {
+	row, err := d.RowReader.Read()
+	if err != nil {
+		return row, err
+	}
+	for i, format := range d.formats {
+		if len(format) == 0 || i >= len(row) {
+			continue
+		}
+		if t, ok := row[i].(time.Time); ok {
+			row[i] = t.Format(format)
+		}
+	}
+	return row, nil
+}
+
+//insertParsedDates inserts the rows copied to the dates table to the table parsing the dates using their date format
+func insertParsedDates(tx *sql.Tx, datesTable string, tablename string, colNames []string, dates map[string]string, logger log.Log) error {
+	d := Dialect{}
+	cols := make([]string, len(colNames))
+	for i, name := range colNames {
+		cols[i] = d.QuoteIdentifier(name)
+		if format, ok := dates[name]; ok {
+			cols[i] = d.ParseDate("NULLIF(TRIM("+cols[i]+"), '')", format)
+		}
+	}
+	logger.Info("inserting the rows with the parsed dates to the table", tablename)
+	qStr := fmt.Sprintf(`INSERT INTO "%s" (%s) SELECT %s FROM "%s"`, tablename, quoteNames(colNames), strings.Join(cols, ", "), datesTable)
+	if _, err := tx.Exec(qStr); err != nil {
+		logger.Error("error while inserting the rows with the parsed dates to the table", tablename)
+		return err
+	}
+	return nil
+}
+
+//mergeStagingTable merges the rows in the staging table with the table using the merge keys in the dump options.
+//A unique index named after the merge keys is created if the table doesn't have a valid unique index on them for finding the conflicting rows.
+//It returns a toolkit.DatastoreError with toolkit.ErrConstraintViolation if the existing rows of the table have duplicate keys.
+//When a key is repeated in the staging table, the row loaded last is merged.
+//The no. of rows inserted, updated and deleted are set in the result
+func mergeStagingTable(tx *sql.Tx, stagingTable string, tablename string, colNames []string, opts toolkit.DumpOptions, result *toolkit.DumpResult, logger log.Log) error {
 	/*
-	 * We will copy the file to the remote
-	 * We will start a transaction for the db operation
-	 * Then we will create the table required
-	 * If required remove the existing data
-	 * Then we will dump the data to the datastore
-	 * Then we will remove the file from the remote
+	 * We will create the unique index on the merge keys if there isn't one and the existing keys are unique
+	 * Then we will upsert the rows from the staging table
+	 * If required we will delete the rows missing in the staging table
 	 */
-	//copying the file to the remote
-	logger.Info("copying the file to remote postgres server", p.DataDumpDirectory)
-	cmdName := "cp"
-	args := []string{filename, p.DataDumpDirectory + "/" + tablename + ".csv"}
-	if doScp {
-		cmdName = "scp"
-		args = []string{"-o", "StrictHostKeyChecking=no", filename, p.DataDumpDirectory + "/" + tablename + ".csv"}
-	}
-	cm := exec.Command(cmdName, args...)
-	err := cm.Run()
+	//creating the unique index if the table doesn't have a valid unique index on the merge keys
+	keys := quoteNames(opts.MergeKeys)
+	sorted := append([]string(nil), opts.MergeKeys...)
+	sort.Strings(sorted)
+	indexed := false
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_index ix WHERE ix.indrelid = to_regclass($1) AND ix.indisunique AND ix.indisvalid `+
+		`AND ix.indpred IS NULL AND ix.indexprs IS NULL AND ix.indnatts = $2 `+
+		`AND ARRAY(SELECT a.attname::text FROM pg_attribute a WHERE a.attrelid = ix.indrelid AND a.attnum = ANY(ix.indkey) ORDER BY 1) = $3)`,
+		regclassName(tablename), len(sorted), pq.Array(sorted)).Scan(&indexed)
 	if err != nil {
-		logger.Error("error copying the file for dumping csv to the datastore", filename, "to", p.DataDumpDirectory)
+		logger.Error("error while finding the unique index on the merge keys of the table", tablename)
 		return err
 	}
+	if !indexed {
+		notNull := make([]string, len(opts.MergeKeys))
+		for i, k := range opts.MergeKeys {
+			notNull[i] = `"` + k + `" IS NOT NULL`
+		}
+		duplicate := ""
+		qStr := fmt.Sprintf(`SELECT ROW(%s)::text FROM "%s" WHERE %s GROUP BY %s HAVING COUNT(*) > 1 LIMIT 1`, keys, tablename, strings.Join(notNull, " AND "), keys)
+		err := tx.QueryRow(qStr).Scan(&duplicate)
+		if err != nil && err != sql.ErrNoRows {
+			logger.Error("error while finding the duplicate merge keys in the table", tablename)
+			return err
+		}
+		if err == nil {
+			logger.Error("the table", tablename, "has duplicate rows for the merge keys", opts.MergeKeys, duplicate)
+			return &toolkit.DatastoreError{Kind: toolkit.ErrConstraintViolation,
+				Message: "the table " + tablename + " has duplicate rows for the merge keys " + strings.Join(opts.MergeKeys, ", ") + " like " + duplicate}
+		}
+		qStr = fmt.Sprintf(`CREATE UNIQUE INDEX "%s" ON "%s" (%s)`, toolkit.MergeIndexName(tablename, opts.MergeKeys), tablename, keys)
+		if _, err := tx.Exec(qStr); err != nil {
+			logger.Error("error while creating the unique index on the merge keys of the table", tablename)
+			return err
+		}
+	}
 
-	//starting the db transaction
-	tx, err := p.DB.BeginTx(context.Background(), nil)
+	//upserting the rows
+	isKey := map[string]bool{}
+	for _, k := range opts.MergeKeys {
+		isKey[k] = true
+	}
+	updates := []string{}
+	for _, name := range colNames {
+		if !isKey[name] {
+			updates = append(updates, fmt.Sprintf(`"%s" = EXCLUDED."%s"`, name, name))
+		}
+	}
+	onConflict := "DO NOTHING"
+	if len(updates) != 0 {
+		onConflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	cols := quoteNames(colNames)
+	qStr := fmt.Sprintf(`WITH merged AS (INSERT INTO "%s" (%s) SELECT DISTINCT ON (%s) %s FROM "%s" ORDER BY %s, ctid DESC ON CONFLICT (%s) %s RETURNING xmax = 0 AS inserted) `+
+		`SELECT COUNT(*) FILTER (WHERE inserted), COUNT(*) FILTER (WHERE NOT inserted) FROM merged`,
+		tablename, cols, keys, cols, stagingTable, keys, keys, onConflict)
+	logger.Info("merging the staging table", stagingTable, "with the table", tablename)
+	if err := tx.QueryRow(qStr).Scan(&result.RowsInserted, &result.RowsUpdated); err != nil {
+		logger.Error("error while merging the staging table", stagingTable, "with the table", tablename)
+		return err
+	}
+
+	//deleting the missing rows
+	if opts.DeleteMissing {
+		matches := make([]string, len(opts.MergeKeys))
+		for i, k := range opts.MergeKeys {
+			matches[i] = fmt.Sprintf(`s."%s" = t."%s"`, k, k)
+		}
+		qStr = fmt.Sprintf(`DELETE FROM "%s" t WHERE NOT EXISTS (SELECT 1 FROM "%s" s WHERE %s)`, tablename, stagingTable, strings.Join(matches, " AND "))
+		res, err := tx.Exec(qStr)
+		if err != nil {
+			logger.Error("error while deleting the rows missing in the merged data from the table", tablename)
+			return err
+		}
+		result.RowsDeleted, err = res.RowsAffected()
+		if err != nil {
+			logger.Error("error while getting the number of rows deleted from the table", tablename)
+			return err
+		}
+	}
+	logger.Info("merged the data with the table", tablename, "inserted:-", result.RowsInserted, "updated:-", result.RowsUpdated, "deleted:-", result.RowsDeleted)
+	return nil
+}
+
+//shadowReplace returns true if the data in the table is to be replaced using a shadow table as per the dump options
+func shadowReplace(opts toolkit.DumpOptions) bool {
+	return opts.ShadowReplace && !opts.AppendData && !opts.CreateTable && len(opts.MergeKeys) == 0
+}
+
+//createShadowTable creates the shadow table for replacing the table without blocking its readers.
+//The shadow table is created like the table with its defaults, constraints and indexes.
+//Tables having dependent views are not replaced as the views would follow the table when it is renamed as its previous version
+func createShadowTable(tx *sql.Tx, tablename string, logger log.Log) (string, error) {
+	shadowTable := toolkit.ShadowTableName(tablename)
+	views, err := dependentViews(tx, tablename)
 	if err != nil {
-		logger.Error("error while creating the db transaction for dumping csv to the datastore")
+		logger.Error("error while finding the views depending on the table", tablename)
+		return "", err
+	}
+	if len(views) != 0 {
+		logger.Error("table", tablename, "having the dependent views", views, "can't be replaced using a shadow table")
+		return "", errors.New("table " + tablename + " can't be replaced using a shadow table as the views " + strings.Join(views, ", ") + " depend on it")
+	}
+	logger.Info("creating the shadow table", shadowTable, "for replacing the table", tablename)
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS "` + shadowTable + `"`); err != nil {
+		logger.Error("error while dropping the existing shadow table", shadowTable)
+		return "", err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE "%s" (LIKE "%s" INCLUDING ALL)`, shadowTable, tablename)); err != nil {
+		logger.Error("error while creating the shadow table", shadowTable, "for replacing the table", tablename)
+		return "", err
+	}
+	return shadowTable, nil
+}
+
+//dependentViews returns the names of the views depending on the table
+func dependentViews(q queryer, tablename string) ([]string, error) {
+	rows, err := q.Query(`SELECT DISTINCT v.relname FROM pg_depend d JOIN pg_rewrite r ON r.oid = d.objid JOIN pg_class v ON v.oid = r.ev_class `+
+		`WHERE d.classid = 'pg_rewrite'::regclass AND d.refclassid = 'pg_class'::regclass AND d.refobjid = to_regclass($1) AND v.oid <> d.refobjid ORDER BY 1`,
+		regclassName(tablename))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	views := []string{}
+	for rows.Next() {
+		name := ""
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		views = append(views, name)
+	}
+	return views, rows.Err()
+}
+
+//swapShadowTable validates the rows in the shadow table and swaps it with the table.
+//If the expected no. of rows is given, the shadow table is swapped only if it has these many rows.
+//The table is renamed as its previous version dropping the existing previous version if any.
+//The table is locked only while being renamed so that its readers are not blocked during the load
+func swapShadowTable(tx *sql.Tx, shadowTable string, tablename string, expectedRows int64, logger log.Log) error {
+	/*
+	 * If required we will validate the no. of rows in the shadow table
+	 * Then we will drop the existing previous version of the table
+	 * Then we will rename the table as the previous version and the shadow table as the table
+	 */
+	//validating the shadow table
+	if expectedRows > 0 {
+		var count int64
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM "` + shadowTable + `"`).Scan(&count); err != nil {
+			logger.Error("error while counting the rows in the shadow table", shadowTable)
+			return err
+		}
+		if count != expectedRows {
+			logger.Error("shadow table", shadowTable, "has", count, "rows. expected", expectedRows)
+			return errors.New("shadow table " + shadowTable + " has " + strconv.FormatInt(count, 10) + " rows. expected " + strconv.FormatInt(expectedRows, 10))
+		}
+	}
+
+	//dropping the existing previous version
+	previousTable := toolkit.PreviousTableName(tablename)
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS "` + previousTable + `"`); err != nil {
+		logger.Error("error while dropping the previous version of the table", previousTable)
 		return err
 	}
 
-	//we will create the table
-	//we will first build the query string to create the table
-	logger.Info("building the table to dump the csv", tablename)
-	var strB strings.Builder
+	//renaming the tables
+	logger.Info("swapping the shadow table", shadowTable, "with the table", tablename)
+	if err := renameTable(tx, tablename, previousTable); err != nil {
+		logger.Error("error while renaming the table", tablename, "as its previous version", previousTable)
+		return err
+	}
+	if err := renameTable(tx, shadowTable, tablename); err != nil {
+		logger.Error("error while renaming the shadow table", shadowTable, "as the table", tablename)
+		return err
+	}
+	return nil
+}
+
+//renameTable renames a table
+func renameTable(tx *sql.Tx, from string, to string) error {
+	_, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, Dialect{}.QuoteIdentifier(from), Dialect{}.QuoteIdentifier(to)))
+	return err
+}
+
+//columnNames returns the names of the columns followed by the json column if given
+func columnNames(columns []interpreter.ColumnNode, jsonColumn string) []string {
+	colNames := make([]string, len(columns))
+	for i, col := range columns {
+		colNames[i] = col.Name
+	}
+	if len(jsonColumn) != 0 {
+		colNames = append(colNames, jsonColumn)
+	}
+	return colNames
+}
+
+//quoteNames returns the comma separated list of the quoted names
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = Dialect{}.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+//columnList returns the quoted list of columns like ( "a", "b" ) for using in the queries
+func columnList(columns []interpreter.ColumnNode) string {
 	var strC strings.Builder
-	strB.WriteString("CREATE TABLE ")
-	strB.WriteString(`"` + tablename + `"`)
-	strB.WriteString("( ")
 	strC.WriteString("(")
 	for k, col := range columns {
 		if k > 0 {
-			strB.WriteString(", ")
 			strC.WriteString(", ")
 		}
-		strB.WriteString("\"" + col.Name + "\" " + convertToPostgresDataType(col.DataType, true))
 		strC.WriteString("\"" + col.Name + "\"")
 	}
-	strB.WriteString(" )")
 	strC.WriteString(" )")
-	//now executing the built query
+	return strC.String()
+}
+
+//copyRows streams the rows from the reader to the table using copy from stdin.
+//The rows are validated against the columns as per the validation mode of the dump options.
+//In ValidationReject mode the invalid and the malformed rows are written to a temporary reject file whose name is returned.
+//The rows copied are added to the progress and the copy is aborted once the dump is cancelled
+func copyRows(tx *sql.Tx, tablename string, colNames []string, columns []interpreter.ColumnNode, rows toolkit.RowReader, opts toolkit.DumpOptions, tracker *toolkit.ProgressTracker) (toolkit.DumpResult, string, error) {
+	result := toolkit.DumpResult{}
 
-	if createTable {
-		_, err = tx.Exec(strB.String())
+	//creating the reject file
+	var rejectW *toolkit.CSVWriter
+	rejectFilename := ""
+	if opts.Validation == toolkit.ValidationReject {
+		reject, err := ioutil.TempFile("", "cuttle-reject-*.csv")
 		if err != nil {
-			logger.Error("error while creating the table", tablename, "for dumping the csv data to the datastore")
-			return err
+			return result, "", err
+		}
+		defer reject.Close()
+		rejectFilename = reject.Name()
+		rejectW, _ = toolkit.NewCSVWriter(reject, toolkit.CSVDialect{})
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn(tablename, colNames...))
+	if err != nil {
+		return result, rejectFilename, err
+	}
+	defer stmt.Close()
+	for {
+		row, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		var malformed *toolkit.RowError
+		if pErr, ok := err.(*csv.ParseError); ok {
+			malformed = &toolkit.RowError{Line: int64(pErr.StartLine), Reason: pErr.Err.Error()}
+		} else if jErr, ok := err.(*toolkit.JSONParseError); ok {
+			malformed = &toolkit.RowError{Line: int64(jErr.Line), Reason: jErr.Err.Error()}
+		}
+		if malformed != nil && (opts.Validation == toolkit.ValidationSkip || opts.Validation == toolkit.ValidationReject) {
+			//malformed rows from the csv and the json lines sources are handled like the invalid rows
+			//with the text of the row as it was in the source
+			malformed.Value = toolkit.RawRow(rows)
+			result.AddRowError(*malformed, opts.MaxRowErrors)
+			if rejectW != nil {
+				rejectW.Write([]interface{}{malformed.Line, "", malformed.Reason, malformed.Value})
+			}
+			continue
+		}
+		if err != nil {
+			return result, rejectFilename, err
+		}
+		if opts.Validation != toolkit.ValidationNone {
+			//values after the columns like the json column are not validated
+			vals := row
+			if len(vals) > len(columns) {
+				vals = vals[:len(columns)]
+			}
+			rErr := toolkit.ValidateRow(columns, vals)
+			if rErr == nil && len(opts.MergeKeys) != 0 {
+				rErr = toolkit.ValidateMergeKeyValues(opts.MergeKeys, columns, vals)
+			}
+			if rErr != nil {
+				rErr.Line = int64(rows.Line())
+				if len(rErr.Column) == 0 {
+					//the whole row is reported as it was read
+					rErr.Value = opts.Dialect.JoinRow(vals)
+				}
+				result.AddRowError(*rErr, opts.MaxRowErrors)
+				if opts.Validation == toolkit.ValidationAbort {
+					return result, rejectFilename, &toolkit.DatastoreError{Kind: toolkit.ErrInvalidValue, Message: "invalid row at line " + strconv.FormatInt(rErr.Line, 10) + " " + rErr.Column + ": " + rErr.Reason}
+				}
+				if rejectW != nil {
+					rejectW.Write(append([]interface{}{rErr.Line, rErr.Column, rErr.Reason}, vals...))
+				}
+				continue
+			}
+		}
+		if _, err := stmt.Exec(row...); err != nil {
+			return result, rejectFilename, err
+		}
+		result.RowsLoaded++
+		if err := tracker.AddRows(1); err != nil {
+			return result, rejectFilename, err
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		return result, rejectFilename, err
+	}
+	if rejectW != nil {
+		if err := rejectW.Flush(); err != nil {
+			return result, rejectFilename, err
 		}
 	}
+	return result, rejectFilename, nil
+}
+
+//rejectTableName returns the name of the table to which the invalid rows are loaded in ValidationReject mode
+func rejectTableName(tablename string, opts toolkit.DumpOptions) string {
+	if len(opts.RejectTable) != 0 {
+		return opts.RejectTable
+	}
+	return tablename + "_rejected_rows"
+}
 
-	if !appendData && !createTable {
-		_, err = tx.Exec("TRUNCATE TABLE \"" + tablename + "\"")
+//loadRejectedRows loads the rows in the reject file created while validating a csv to the reject table.
+//The reject table has the line, column and reason of the row error followed by the columns as text.
+//If the data is not appended, the reject table is recreated
+func loadRejectedRows(tx *sql.Tx, rejectFilename string, rejectTable string, columns []interpreter.ColumnNode, appendData bool) error {
+	/*
+	 * We will recreate the reject table if required
+	 * Then we will create the reject table
+	 * Then we will copy the rows in the reject file to the table
+	 */
+	//recreating the reject table
+	if !appendData {
+		_, err := tx.Exec("DROP TABLE IF EXISTS \"" + rejectTable + "\"")
 		if err != nil {
-			logger.Error("error while truncating the table", tablename, "for replacing the csv data in the datastore")
 			return err
 		}
 	}
 
-	//now we will dump the data to the datastore
-	fileNameSplitted := strings.Split(filename, string([]rune{filepath.Separator}))
-	if len(fileNameSplitted) < 1 {
-		logger.Error("expected the filename to have atleast 1 part. got", len(fileNameSplitted))
-		return errors.New("expected the filename to have atleast 1 part. got " + strconv.Itoa(len(fileNameSplitted)))
+	//creating the reject table
+	colNames := []string{"_line", "_column", "_reason"}
+	var strB strings.Builder
+	strB.WriteString("CREATE TABLE IF NOT EXISTS \"" + rejectTable + "\" ( \"_line\" bigint, \"_column\" text, \"_reason\" text")
+	for _, col := range columns {
+		strB.WriteString(", \"" + col.Name + "\" text")
+		colNames = append(colNames, col.Name)
 	}
-	remoteFileName := p.DataDumpDirectory + "/" + tablename + ".csv"
-	remoteFileNameSplitted := strings.Split(remoteFileName, ":")
-	if len(remoteFileNameSplitted) < 2 {
-		logger.Error("expected the filename to have server info like user@server.com:/home/user. Couldn't find one", remoteFileName)
-		return errors.New("expected the filename to have server info like user@server.com:/home/user. Couldn't find one + remoteFileName")
+	strB.WriteString(" )")
+	_, err := tx.Exec(strB.String())
+	if err != nil {
+		return err
 	}
-	remoteFileNameWithoutServer := remoteFileNameSplitted[len(remoteFileNameSplitted)-1]
 
-	logger.Info("copying the data from the csv to the table", remoteFileNameWithoutServer, tablename)
-	qStr := fmt.Sprintf(`COPY "%s" %s FROM '%s' DELIMITER ',' CSV HEADER;`, tablename, strC.String(), remoteFileNameWithoutServer)
-	result, err := tx.Exec(qStr)
+	//copying the rows to the table
+	f, err := os.Open(rejectFilename)
 	if err != nil {
-		logger.Error("error while dumping to the table", tablename, "from csv", remoteFileNameWithoutServer)
 		return err
 	}
-	ef, err := result.RowsAffected()
+	defer f.Close()
+	stmt, err := tx.Prepare(pq.CopyIn(rejectTable, colNames...))
 	if err != nil {
-		logger.Error("error while getting the number of rows affected while dumping the data to the datastore")
 		return err
 	}
-
-	err = tx.Commit()
+	defer stmt.Close()
+	r, err := toolkit.NewCSVReader(f, toolkit.CSVDialect{})
 	if err != nil {
-		logger.Error("error while commiting the changes")
 		return err
 	}
+	for {
+		row, err := r.ReadRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		//rows with a different no. of fields are fitted to the columns of the reject table
+		vals := make([]interface{}, len(colNames))
+		copy(vals, row)
+		if _, err := stmt.Exec(vals...); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.Exec()
+	return err
+}
+
+//RestorePreviousTable restores the previous version of a table replaced using a shadow table.
+//The restored version is swapped with the current one so that the restore can be undone by restoring again
+func (p Postgres) RestorePreviousTable(tablename string) error {
+	/*
+	 * We will start a transaction for the db operation
+	 * Then we will check whether the previous version exists
+	 * Then we will swap the table with its previous version
+	 */
+	//starting the db transaction
+	tx, err := p.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return TranslateError(err)
+	}
+	defer tx.Rollback()
 
-	//removing the file from remote
-	logger.Info("removing the data file from the remote server")
-	rmCmd := exec.Command("ssh", remoteFileNameSplitted[0], "rm", remoteFileNameWithoutServer)
-	err = rmCmd.Run()
+	//checking whether the previous version exists
+	previousTable := toolkit.PreviousTableName(tablename)
+	exists, err := tableExists(tx, previousTable)
 	if err != nil {
-		logger.Error("error removing the file from the server after dumping csv to the datastore", remoteFileName)
-		return err
+		return TranslateError(err)
+	}
+	if !exists {
+		return &toolkit.TableError{Op: "restore", Table: toolkit.PreviousTableName(tablename), Err: toolkit.ErrTableNotFound}
 	}
 
-	logger.Info("successfully dumped the csv to the table", filename, tablename, "copied no. of rows:-", ef)
-	return nil
+	//swapping the tables
+	shadowTable := toolkit.ShadowTableName(tablename)
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS "` + shadowTable + `"`); err != nil {
+		return TranslateError(err)
+	}
+	if err := renameTable(tx, tablename, shadowTable); err != nil {
+		return TranslateError(err)
+	}
+	if err := renameTable(tx, previousTable, tablename); err != nil {
+		return TranslateError(err)
+	}
+	if err := renameTable(tx, shadowTable, previousTable); err != nil {
+		return TranslateError(err)
+	}
+	return TranslateError(tx.Commit())
 }
 
-//DeleteTable deletes the table from the datastore
-func (p Postgres) DeleteTable(tablename string) error {
-	_, err := p.DB.Exec("drop table \"" + tablename + "\"")
-	return err
+//DropPreviousTable drops the previous version of a table replaced using a shadow table if exists
+func (p Postgres) DropPreviousTable(tablename string) error {
+	_, err := p.DB.Exec(`DROP TABLE IF EXISTS "` + toolkit.PreviousTableName(tablename) + `"`)
+	return TranslateError(err)
 }
 
 //Exec will execute a query in the post gres
 func (p Postgres) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	span := toolkit.StartOperation(p.Observer, toolkit.OperationExec, "")
+	results, err := p.exec(query, args...)
+	span.Finish(int64(len(results)), err)
+	return results, err
+}
+
+//exec runs the query returning the rows as maps of the column names to their values
+func (p Postgres) exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
 	/*
 	 * We will add a db check
 	 * Then we will query the datastore
@@ -209,13 +1586,13 @@ func (p Postgres) Exec(query string, args ...interface{}) ([]map[string]interfac
 	//db check
 	if p.DB == nil {
 		//couldn't connect to the postgres since no connection available
-		return nil, errors.New("couldn't find the datastore connection to the postgres")
+		return nil, &toolkit.DatastoreError{Kind: toolkit.ErrConnection, Message: "couldn't find the datastore connection to the postgres"}
 	}
 
 	//datastore query
 	rows, err := p.DB.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, TranslateError(err)
 	}
 	defer rows.Close()
 
@@ -223,7 +1600,7 @@ func (p Postgres) Exec(query string, args ...interface{}) ([]map[string]interfac
 	results := []map[string]interface{}{}
 	cols, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, TranslateError(err)
 	}
 	for rows.Next() {
 		result := map[string]interface{}{}
@@ -233,7 +1610,7 @@ func (p Postgres) Exec(query string, args ...interface{}) ([]map[string]interfac
 			vals[i] = &v
 		}
 		if err := rows.Scan(vals...); err != nil {
-			return nil, err
+			return nil, TranslateError(err)
 		}
 		for i, v := range vals {
 			result[cols[i]] = v
@@ -243,14 +1620,31 @@ func (p Postgres) Exec(query string, args ...interface{}) ([]map[string]interfac
 
 	// Check for errors from iterating over rows.
 	if err := rows.Err(); err != nil {
-		return results, err
+		return results, TranslateError(err)
 	}
 	return results, nil
 }
 
+//Dialect returns the dialect of postgres for generating the queries
+func (p Postgres) Dialect() toolkit.Dialect {
+	return Dialect{}
+}
+
 //GetColumnTypes returns the column types of the given table name
 func (p Postgres) GetColumnTypes(tableName string) ([]toolkit.Column, error) {
-	rows, err := p.DB.Query("SELECT column_name, data_type FROM information_schema.columns WHERE table_name = '" + tableName + "'")
+	columns, err := columnTypes(p.DB, tableName)
+	return columns, TranslateError(err)
+}
+
+//queryer can run the queries returning rows. Both the db connection and the transactions are queryers
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//columnTypes returns the column types of the given table name in their order in the table
+func columnTypes(q queryer, tableName string) ([]toolkit.Column, error) {
+	rows, err := q.Query("SELECT column_name, data_type FROM information_schema.columns WHERE table_name = $1 ORDER BY ordinal_position", tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -274,8 +1668,112 @@ func (p Postgres) GetColumnTypes(tableName string) ([]toolkit.Column, error) {
 
 //ChangeColumnTypeToDate changes a given column's data type to date with the date format as provided
 func (p Postgres) ChangeColumnTypeToDate(tableName string, colName string, dateFormat string) error {
-	_, err := p.DB.Exec("ALTER TABLE \"" + tableName + "\" ALTER COLUMN \"" + colName + "\" TYPE DATE using to_date(\"" + colName + "\", '" + convertToPostgresFormat(dateFormat) + "')")
-	return err
+	d := Dialect{}
+	col := d.QuoteIdentifier(colName)
+	span := toolkit.StartOperation(p.Observer, toolkit.OperationChangeColumnTypeToDate, tableName)
+	_, err := p.DB.Exec("ALTER TABLE " + d.QuoteIdentifier(tableName) + " ALTER COLUMN " + col + " TYPE " + d.DataType(interpreter.DataTypeDate) + " using " + d.ParseDate(col, dateFormat))
+	span.Finish(0, err)
+	return TranslateError(err)
+}
+
+//ChangeColumnTypeToDateWithRejects changes a given column's data type to date with the date format as provided.
+//The values that can't be parsed with the date format are copied to the reject table with the primary key of their row as the row identifier
+//and are set to null in the converted column. The row identifier is null for the tables without a primary key
+//as the physical location of the row changes when the table is rewritten. It returns the number of rows rejected
+func (p Postgres) ChangeColumnTypeToDateWithRejects(tableName string, colName string, dateFormat string, rejectTable string) (int64, error) {
+	span := toolkit.StartOperation(p.Observer, toolkit.OperationChangeColumnTypeToDate, tableName)
+	rejected, err := p.changeColumnTypeToDateWithRejects(tableName, colName, dateFormat, rejectTable)
+	span.Finish(rejected, err)
+	return rejected, err
+}
+
+//changeColumnTypeToDateWithRejects changes the data type of the column to date copying the values that can't be parsed to the reject table
+func (p Postgres) changeColumnTypeToDateWithRejects(tableName string, colName string, dateFormat string, rejectTable string) (int64, error) {
+	/*
+	 * We will start a transaction
+	 * Then we will create the reject table if it doesn't exist
+	 * Then we will create the function to parse the dates without failing
+	 * Then we will find the primary key of the table to identify the rows. Rows of the tables without one are identified by their values
+	 * Then we will copy the values that can't be parsed to the reject table
+	 * Then we will change the column type setting the values that can't be parsed to null
+	 * Finally we will commit the changes
+	 */
+	//starting the db transaction
+	tx, err := p.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	//creating the reject table
+	_, err = tx.Exec("CREATE TABLE IF NOT EXISTS \"" + rejectTable + "\" (table_name text, column_name text, row_id text, value text, reason text, rejected_at timestamp DEFAULT now())")
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+
+	//creating the function to parse the dates. It is created in the temporary schema so that it is dropped with the session
+	_, err = tx.Exec(`CREATE OR REPLACE FUNCTION pg_temp.cuttle_try_to_date(val text, fmt text) RETURNS date AS $$
+	BEGIN
+		RETURN to_date(val, fmt);
+	EXCEPTION WHEN others THEN
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql`)
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+
+	//finding the primary key of the table
+	desc, err := describeTable(tx, tableName)
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+	rowID := RowIdentifier("t", nil)
+	for _, c := range desc.Constraints {
+		if c.Type == toolkit.ConstraintPrimaryKey && len(c.Columns) != 0 {
+			rowID = RowIdentifier("t", c.Columns)
+			break
+		}
+	}
+
+	//copying the values that can't be parsed to the reject table
+	pgFormat := convertToPostgresFormat(dateFormat)
+	result, err := tx.Exec("INSERT INTO \""+rejectTable+"\" (table_name, column_name, row_id, value, reason) "+
+		"SELECT $1, $2, "+rowID+", t.\""+colName+"\", $3 FROM \""+tableName+"\" AS t "+
+		"WHERE NULLIF(TRIM(t.\""+colName+"\"), '') IS NOT NULL AND pg_temp.cuttle_try_to_date(t.\""+colName+"\", $4) IS NULL",
+		tableName, colName, "couldn't parse the value as a date with the format "+dateFormat, pgFormat)
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+	rejected, err := result.RowsAffected()
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+
+	//changing the column type
+	_, err = tx.Exec("ALTER TABLE \"" + tableName + "\" ALTER COLUMN \"" + colName + "\" TYPE DATE using pg_temp.cuttle_try_to_date(NULLIF(TRIM(\"" + colName + "\"), ''), '" + pgFormat + "')")
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+
+	return rejected, TranslateError(tx.Commit())
+}
+
+//RowIdentifier returns the expression identifying a row of the table with the given alias by the given key columns as text.
+//A single column key is its value and a composite key is its values as a row like (1,north).
+//Without the key columns the row is identified by all its values as a json object like {"id":1,"region":"north"}
+func RowIdentifier(alias string, keys []string) string {
+	if len(keys) == 0 {
+		return "row_to_json(" + Dialect{}.QuoteIdentifier(alias) + ")::text"
+	}
+	qualified := make([]string, len(keys))
+	for i, k := range keys {
+		qualified[i] = Dialect{}.QuoteIdentifier(alias) + "." + Dialect{}.QuoteIdentifier(k)
+	}
+	if len(keys) == 1 {
+		return qualified[0] + "::text"
+	}
+	return "ROW(" + strings.Join(qualified, ", ") + ")::text"
 }
 
 func convertToPostgresFormat(dateFormat string) string {
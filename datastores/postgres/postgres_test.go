@@ -6,11 +6,16 @@
 package postgres_test
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/cuttle-ai/brain/env"
 	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
 	"github.com/cuttle-ai/db-toolkit/datastores/postgres"
 	"github.com/cuttle-ai/octopus/interpreter"
 )
@@ -32,7 +37,11 @@ func TestDumpCSV(t *testing.T) {
 		return
 	}
 
-	conn.DB.Exec("drop table if exists groceries")
+	err = conn.DropTableIfExists("groceries")
+	if err != nil {
+		t.Error("error while dropping the existing table", err)
+		return
+	}
 
 	err = conn.DumpCSV(testdataFile, "groceries", []interpreter.ColumnNode{
 		{Name: "item"},
@@ -46,3 +55,278 @@ func TestDumpCSV(t *testing.T) {
 		return
 	}
 }
+
+//testDatastore connects to the test database configured in the environment. The test is skipped if the database isn't configured
+func testDatastore(t *testing.T) *postgres.Postgres {
+	env.LoadEnv(log.NewLogger())
+	if len(os.Getenv("DB_HOST")) == 0 {
+		t.Skip("the test database isn't configured")
+	}
+	conn, err := postgres.NewPostgres(os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_NAME"),
+		os.Getenv("DB_USERNAME"), os.Getenv("DB_PASSWORD"), os.Getenv("SERVER_DATA_DUMP_DIRECTORY"))
+	if err != nil {
+		t.Fatal("error in connecting to the datastore", err)
+	}
+	return conn
+}
+
+func TestRowIdentifier(t *testing.T) {
+	cases := []struct {
+		keys     []string
+		expected string
+	}{
+		{nil, `row_to_json("t")::text`},
+		{[]string{"id"}, `"t"."id"::text`},
+		{[]string{"id", "region"}, `ROW("t"."id", "t"."region")::text`},
+	}
+	for _, c := range cases {
+		if id := postgres.RowIdentifier("t", c.keys); id != c.expected {
+			t.Error("expected the row identifier", c.expected, "for the keys", c.keys, "got", id)
+			return
+		}
+	}
+}
+
+func TestChangeColumnTypeToDateWithRejects(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_dates")
+	conn.DropTableIfExists("sales_dates_rejects")
+	defer conn.DropTableIfExists("sales_dates")
+	defer conn.DropTableIfExists("sales_dates_rejects")
+	_, err := conn.DB.Exec(`CREATE TABLE sales_dates (region text, sold_on text);` +
+		`INSERT INTO sales_dates VALUES ('north', '2020-01-02'), ('south', 'yesterday')`)
+	if err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+
+	rejected, err := conn.ChangeColumnTypeToDateWithRejects("sales_dates", "sold_on", "2006-01-02", "sales_dates_rejects")
+	if err != nil || rejected != 1 {
+		t.Error("expected one row to be rejected. got", rejected, err)
+		return
+	}
+	rowID, value := "", ""
+	err = conn.DB.QueryRow(`SELECT row_id, value FROM sales_dates_rejects WHERE table_name = 'sales_dates'`).Scan(&rowID, &value)
+	if err != nil {
+		t.Error("error while reading the reject table", err)
+		return
+	}
+	if rowID != `{"region":"south","sold_on":"yesterday"}` || value != "yesterday" {
+		t.Error("expected the rejected row to be identified by its values. got", rowID, value)
+	}
+}
+
+func TestDumpRowsRejectsMalformedRows(t *testing.T) {
+	conn := testDatastore(t)
+	defer conn.DropTableIfExists("groceries_rejects")
+	defer conn.DropTableIfExists("groceries_rejects_rejected_rows")
+	columns := []interpreter.ColumnNode{{Name: "item"}, {Name: "quantity", DataType: interpreter.DataTypeInt}}
+	opts := toolkit.DumpOptions{CreateTable: true, Validation: toolkit.ValidationReject}
+
+	rows, _ := toolkit.NewCSVRowReader(strings.NewReader("item,quantity\napple,1\nbanana,two\n\"carrot,3\n"), toolkit.CSVDialect{})
+	result, err := conn.DumpRows(rows, "groceries_rejects", columns, opts, log.NewLogger())
+	if err != nil {
+		t.Error("error while dumping the rows", err)
+		return
+	}
+	count := int64(0)
+	if err := conn.DB.QueryRow(`SELECT count(*) FROM groceries_rejects_rejected_rows`).Scan(&count); err != nil {
+		t.Error("error while counting the rejected rows", err)
+		return
+	}
+	if result.RowsRejected != 2 || count != result.RowsRejected {
+		t.Error("expected the 2 rejected rows in the reject table. got", count, "rows for", result.RowsRejected, "rejected")
+		return
+	}
+	raw := ""
+	if err := conn.DB.QueryRow(`SELECT item FROM groceries_rejects_rejected_rows WHERE _line = 4`).Scan(&raw); err != nil || raw != `"carrot,3` {
+		t.Error("expected the text of the malformed row in the reject table. got", raw, err)
+	}
+}
+
+func TestDumpCSVResumableDates(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_resumed")
+	defer conn.DropTableIfExists("sales_resumed")
+	if _, err := conn.DB.Exec(`CREATE TABLE sales_resumed (region text, sold_on date)`); err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+	f, err := ioutil.TempFile("", "sales-*.csv")
+	if err != nil {
+		t.Error("error while creating the test csv", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("region,sold_on\nnorth,02/01/2020\nsouth,\n")
+	f.Close()
+
+	columns := []interpreter.ColumnNode{{Name: "region"}, {Name: "sold_on", DataType: interpreter.DataTypeDate, DateFormat: "02/01/2006"}}
+	_, err = conn.DumpCSVResumable(f.Name(), "sales_resumed", columns, toolkit.DumpOptions{AppendData: true, ChunkRows: 1}, log.NewLogger())
+	if err != nil {
+		t.Error("error while dumping the csv", err)
+		return
+	}
+	soldOn := ""
+	if err := conn.DB.QueryRow(`SELECT sold_on::text FROM sales_resumed WHERE region = 'north'`).Scan(&soldOn); err != nil || soldOn != "2020-01-02" {
+		t.Error("expected the date to be parsed with its date format. got", soldOn, err)
+	}
+}
+
+func TestDumpRowsMerge(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_merged")
+	defer conn.DropTableIfExists("sales_merged")
+	_, err := conn.DB.Exec(`CREATE TABLE sales_merged (id text, region text);` +
+		`INSERT INTO sales_merged VALUES ('1', 'north'), ('2', 'south')`)
+	if err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+	columns := []interpreter.ColumnNode{{Name: "id"}, {Name: "region"}}
+	opts := toolkit.DumpOptions{AppendData: true, MergeKeys: []string{"id"}, DeleteMissing: true}
+
+	rows, _ := toolkit.NewCSVRowReader(strings.NewReader("id,region\n2,east\n3,west\n"), toolkit.CSVDialect{})
+	result, err := conn.DumpRows(rows, "sales_merged", columns, opts, log.NewLogger())
+	if err != nil {
+		t.Error("error while merging the rows", err)
+		return
+	}
+	if result.RowsInserted != 1 || result.RowsUpdated != 1 || result.RowsDeleted != 1 {
+		t.Error("expected 1 row each to be inserted, updated and deleted. got", result.RowsInserted, result.RowsUpdated, result.RowsDeleted)
+		return
+	}
+	regions := ""
+	if err := conn.DB.QueryRow(`SELECT string_agg(id || ':' || region, ',' ORDER BY id) FROM sales_merged`).Scan(&regions); err != nil || regions != "2:east,3:west" {
+		t.Error("expected the merged rows 2:east,3:west. got", regions, err)
+	}
+}
+
+func TestDumpRowsMergeDuplicateKeys(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_duplicates")
+	defer conn.DropTableIfExists("sales_duplicates")
+	_, err := conn.DB.Exec(`CREATE TABLE sales_duplicates (id text, region text);` +
+		`INSERT INTO sales_duplicates VALUES ('1', 'north'), ('1', 'south')`)
+	if err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+	columns := []interpreter.ColumnNode{{Name: "id"}, {Name: "region"}}
+	rows, _ := toolkit.NewCSVRowReader(strings.NewReader("id,region\n2,east\n"), toolkit.CSVDialect{})
+	_, err = conn.DumpRows(rows, "sales_duplicates", columns, toolkit.DumpOptions{AppendData: true, MergeKeys: []string{"id"}}, log.NewLogger())
+	if !errors.Is(err, toolkit.ErrConstraintViolation) || !strings.Contains(err.Error(), "id") {
+		t.Error("expected a constraint violation naming the merge keys. got", err)
+	}
+}
+
+func TestDumpCSVResumableMergeNullKeys(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_resumed_merge")
+	defer conn.DropTableIfExists("sales_resumed_merge")
+	defer conn.DropTableIfExists(toolkit.ResumeTableName("sales_resumed_merge"))
+	if _, err := conn.DB.Exec(`CREATE TABLE sales_resumed_merge (id text, region text)`); err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+	f, err := ioutil.TempFile("", "sales-*.csv")
+	if err != nil {
+		t.Error("error while creating the test csv", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("id,region\n,north\n,south\n")
+	f.Close()
+
+	columns := []interpreter.ColumnNode{{Name: "id"}, {Name: "region"}}
+	opts := toolkit.DumpOptions{AppendData: true, MergeKeys: []string{"id"}, Validation: toolkit.ValidationNone}
+	if _, err := conn.DumpCSVResumable(f.Name(), "sales_resumed_merge", columns, opts, log.NewLogger()); err == nil {
+		t.Error("expected the rows with null merge keys to fail the resumable merge")
+	}
+}
+
+func TestDumpRowsShadowReplace(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_shadow")
+	defer conn.DropTableIfExists("sales_shadow")
+	defer conn.DropTableIfExists(toolkit.PreviousTableName("sales_shadow"))
+	_, err := conn.DB.Exec(`CREATE TABLE sales_shadow (id text, region text);` +
+		`INSERT INTO sales_shadow VALUES ('1', 'north')`)
+	if err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+	columns := []interpreter.ColumnNode{{Name: "id"}, {Name: "region"}}
+	newRows := func() toolkit.RowReader {
+		rows, _ := toolkit.NewCSVRowReader(strings.NewReader("id,region\n2,east\n3,west\n"), toolkit.CSVDialect{})
+		return rows
+	}
+	count := func() int64 {
+		c := int64(0)
+		conn.DB.QueryRow(`SELECT COUNT(*) FROM sales_shadow`).Scan(&c)
+		return c
+	}
+
+	//shadow table not having the expected rows isn't swapped
+	_, err = conn.DumpRows(newRows(), "sales_shadow", columns, toolkit.DumpOptions{ShadowReplace: true, ExpectedRows: 3}, log.NewLogger())
+	if err == nil || count() != 1 {
+		t.Error("expected the shadow table with fewer rows than expected not to be swapped. got", count(), "rows", err)
+		return
+	}
+
+	//tables having the dependent views are not replaced
+	if _, err := conn.DB.Exec(`CREATE VIEW sales_shadow_view AS SELECT * FROM sales_shadow`); err != nil {
+		t.Error("error while creating the view", err)
+		return
+	}
+	_, err = conn.DumpRows(newRows(), "sales_shadow", columns, toolkit.DumpOptions{ShadowReplace: true}, log.NewLogger())
+	conn.DB.Exec(`DROP VIEW sales_shadow_view`)
+	if err == nil || !strings.Contains(err.Error(), "sales_shadow_view") || count() != 1 {
+		t.Error("expected the table having a dependent view not to be replaced. got", count(), "rows", err)
+		return
+	}
+
+	//shadow table having the expected rows is swapped
+	_, err = conn.DumpRows(newRows(), "sales_shadow", columns, toolkit.DumpOptions{ShadowReplace: true, ExpectedRows: 2}, log.NewLogger())
+	if err != nil || count() != 2 {
+		t.Error("expected the table to be replaced with the 2 rows. got", count(), "rows", err)
+	}
+}
+
+func TestExportQueryCSV(t *testing.T) {
+	conn := testDatastore(t)
+	conn.DropTableIfExists("sales_exported")
+	defer conn.DropTableIfExists("sales_exported")
+	_, err := conn.DB.Exec(`CREATE TABLE sales_exported (id text, sold date);` +
+		`INSERT INTO sales_exported VALUES ('1', '2020-01-02'), ('2;3', NULL)`)
+	if err != nil {
+		t.Error("error while creating the table", err)
+		return
+	}
+	opts := toolkit.ExportOptions{Dialect: toolkit.CSVDialect{Delimiter: ';', NullString: "NA"}}
+
+	//the table is copied to stdout
+	b := &bytes.Buffer{}
+	if err := conn.ExportCSV(b, "sales_exported", opts); err != nil {
+		t.Error("error while exporting the table", err)
+		return
+	}
+	if b.String() != "id;sold\n1;2020-01-02\n\"2;3\";NA\n" {
+		t.Error("expected the table to be exported in the dialect. got", b.String())
+		return
+	}
+
+	//the rows of the queries with arguments are streamed
+	b.Reset()
+	err = conn.ExportQueryCSV(b, toolkit.ExportOptions{Header: []string{"sold on"}}, `SELECT sold FROM sales_exported WHERE id = $1`, "1")
+	if err != nil || b.String() != "sold on\n2020-01-02\n" {
+		t.Error("expected the query result to be exported with the header. got", b.String(), err)
+		return
+	}
+
+	//the queries modifying the data are refused
+	err = conn.ExportQueryCSV(ioutil.Discard, opts, `WITH d AS (DELETE FROM sales_exported RETURNING *) SELECT * FROM d`)
+	if err == nil {
+		t.Error("expected the query modifying the data to fail in the read only transaction")
+	}
+}
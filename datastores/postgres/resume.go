@@ -0,0 +1,403 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//checkpoint has the progress of a resumable ingestion committed to the checkpoint table
+type checkpoint struct {
+	//chunk is the no. of chunks committed
+	chunk int64
+	//rowsRead is the no. of rows read from the source including the rejected ones
+	rowsRead int64
+	//rowsLoaded is the no. of rows loaded to the resume table
+	rowsLoaded int64
+	//rowsRejected is the no. of rows that failed the validation
+	rowsRejected int64
+}
+
+//DumpCSVResumable will dump the given csv file to the postgres instance in chunks as per the dump options.
+//Each chunk is copied to the resume table of the table and is committed along with a checkpoint in the checkpoint table.
+//If the dump is interrupted, calling it again with the same file resumes it after the last committed chunk.
+//The rows already committed are read again from the file but are not loaded.
+//Once all the chunks are committed, the rows in the resume table are moved to the table in a single transaction
+//so that the table has the same data as a single dump. If no columns are given, the columns in the header of the file are used
+func (p Postgres) DumpCSVResumable(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	return toolkit.ObserveDump(p.Observer, toolkit.OperationDumpCSVResumable, tablename, opts, func(opts toolkit.DumpOptions) (toolkit.DumpResult, error) {
+		return p.dumpCSVResumable(filename, tablename, columns, opts, logger)
+	})
+}
+
+//dumpCSVResumable dumps the csv file to the table in chunks as per the dump options
+func (p Postgres) dumpCSVResumable(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	/*
+	 * We will open the file
+	 * Then we will find the checkpoint of the ingestion to resume or start a new one
+	 * Then we will skip the rows already committed
+	 * Then we will commit the rest of the rows in chunks along with the checkpoints
+	 * Finally we will move the rows from the resume table to the table
+	 */
+	result := toolkit.DumpResult{}
+	tracker := toolkit.NewProgressTracker(opts)
+	ctx := tracker.Context()
+
+	//opening the file
+	fingerprint, err := toolkit.SourceFingerprint(filename)
+	if err != nil {
+		logger.Error("error while finding the fingerprint of the csv file", filename)
+		return result, TranslateError(err)
+	}
+	r, err := toolkit.OpenDecompressed(filename, "")
+	if err != nil {
+		logger.Error("error while opening the csv file", filename)
+		return result, TranslateError(err)
+	}
+	defer r.Close()
+	if info, err := os.Stat(filename); err == nil {
+		tracker.SetTotal(info.Size(), 0)
+	}
+	rows, err := toolkit.NewCSVRowReader(tracker.Reader(r), opts.Dialect)
+	if err != nil {
+		logger.Error("error while reading the header of the csv file", filename)
+		return result, TranslateError(err)
+	}
+	if len(columns) == 0 {
+		columns = rows.Columns()
+	}
+	if len(opts.MergeKeys) != 0 {
+		if err := toolkit.ValidateMergeKeys(opts.MergeKeys, columns); err != nil {
+			logger.Error("invalid merge keys for merging the data to the table", tablename)
+			return result, TranslateError(err)
+		}
+	}
+
+	//finding the checkpoint
+	if err := tracker.Phase(toolkit.PhaseCreate); err != nil {
+		return result, TranslateError(err)
+	}
+	cp, err := p.startCheckpoint(ctx, tablename, fingerprint, columns, opts, logger)
+	if err != nil {
+		logger.Error("error while finding the checkpoint for ingesting the csv to the table", tablename)
+		return result, TranslateError(err)
+	}
+
+	//skipping the rows already committed
+	if err := tracker.Phase(toolkit.PhaseCopy); err != nil {
+		return result, TranslateError(err)
+	}
+	chunks := toolkit.NewChunkReader(rows, int64(opts.ChunkRows))
+	if cp.rowsRead > 0 {
+		logger.Info("resuming the ingestion to the table", tablename, "after the chunk", cp.chunk, "skipping no. of rows:-", cp.rowsRead)
+		if err := chunks.Skip(cp.rowsRead); err != nil {
+			logger.Error("error while skipping the rows already committed to the table", tablename)
+			return result, TranslateError(err)
+		}
+	}
+
+	//committing the chunks
+	for chunks.Next() {
+		chunkResult, err := p.commitChunk(ctx, chunks, &cp, tablename, columns, opts, tracker, logger)
+		for _, rErr := range chunkResult.RowErrors {
+			result.AddRowError(rErr, opts.MaxRowErrors)
+		}
+		if err != nil {
+			logger.Error("error while committing the chunk", cp.chunk+1, "of the csv to the table", tablename)
+			return result, TranslateError(err)
+		}
+	}
+	tracker.Done()
+
+	//moving the rows to the table
+	if err := tracker.Phase(toolkit.PhaseCleanup); err != nil {
+		return result, TranslateError(err)
+	}
+	err = p.finishCheckpoint(ctx, tablename, columns, cp, opts, &result, logger)
+	result.RowsLoaded = cp.rowsLoaded
+	result.RowsRejected = cp.rowsRejected
+	if err != nil {
+		logger.Error("error while moving the ingested rows from the resume table to the table", tablename)
+		return result, TranslateError(err)
+	}
+	tracker.Done()
+	logger.Info("successfully ingested the csv to the table", filename, tablename, "in no. of chunks:-", cp.chunk, "copied no. of rows:-", cp.rowsLoaded)
+	return result, nil
+}
+
+//startCheckpoint returns the checkpoint of the ingestion to the table if it is of the file with the same fingerprint.
+//Else a new ingestion is started by recreating the resume table and the checkpoint.
+//The resume table has the columns of the table evolved for the data if required. The table itself is evolved only by finishCheckpoint
+func (p Postgres) startCheckpoint(ctx context.Context, tablename string, fingerprint string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (checkpoint, error) {
+	/*
+	 * We will start a transaction for the db operation
+	 * Then we will create the checkpoint table if not exists
+	 * Then we will find the existing checkpoint of the same file
+	 * Else we will recreate the resume table with the evolved schema and the checkpoint
+	 */
+	//starting the db transaction
+	cp := checkpoint{}
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return cp, err
+	}
+	defer tx.Rollback()
+
+	//creating the checkpoint table
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS "` + toolkit.CheckpointTableName + `" (table_name text PRIMARY KEY, fingerprint text NOT NULL, ` +
+		`chunk bigint NOT NULL, rows_read bigint NOT NULL, rows_loaded bigint NOT NULL, rows_rejected bigint NOT NULL, updated_at timestamp DEFAULT now())`)
+	if err != nil {
+		logger.Error("error while creating the checkpoint table", toolkit.CheckpointTableName)
+		return cp, err
+	}
+
+	//finding the existing checkpoint
+	existing := ""
+	err = tx.QueryRow(`SELECT fingerprint, chunk, rows_read, rows_loaded, rows_rejected FROM "`+toolkit.CheckpointTableName+`" WHERE table_name = $1 FOR UPDATE`, tablename).
+		Scan(&existing, &cp.chunk, &cp.rowsRead, &cp.rowsLoaded, &cp.rowsRejected)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("error while getting the checkpoint of the table", tablename)
+		return cp, err
+	}
+	if err == nil && existing == fingerprint {
+		return cp, tx.Commit()
+	}
+	if err == nil {
+		logger.Info("discarding the checkpoint of the table", tablename, "as it is of a different file")
+	}
+
+	//recreating the resume table and the checkpoint
+	cp = checkpoint{}
+	resumeTable := toolkit.ResumeTableName(tablename)
+	logger.Info("creating the resume table", resumeTable, "for ingesting the data to the table", tablename)
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS "` + resumeTable + `"`); err != nil {
+		logger.Error("error while dropping the existing resume table", resumeTable)
+		return cp, err
+	}
+	if opts.CreateTable {
+		err = prepareTable(tx, resumeTable, columns, "", toolkit.DumpOptions{CreateTable: true}, logger)
+	} else {
+		//the resume table has the same data types as the table after evolving its schema so that the rows can be moved as it is
+		var cols []string
+		cols, err = resumeColumns(tx, tablename, columns, opts)
+		if err == nil {
+			_, err = tx.Exec(fmt.Sprintf(`CREATE TABLE "%s" AS SELECT %s FROM "%s" WITH NO DATA`, resumeTable, strings.Join(cols, ", "), tablename))
+		}
+	}
+	if err == nil && len(opts.MergeKeys) != 0 {
+		//the resume table is merged like a staging table
+		err = disallowNullKeys(tx, resumeTable, opts.MergeKeys, logger)
+	}
+	if err != nil {
+		logger.Error("error while creating the resume table", resumeTable)
+		return cp, err
+	}
+	_, err = tx.Exec(`INSERT INTO "`+toolkit.CheckpointTableName+`" (table_name, fingerprint, chunk, rows_read, rows_loaded, rows_rejected) VALUES ($1, $2, 0, 0, 0, 0) `+
+		`ON CONFLICT (table_name) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, chunk = 0, rows_read = 0, rows_loaded = 0, rows_rejected = 0, updated_at = now()`,
+		tablename, fingerprint)
+	if err != nil {
+		logger.Error("error while creating the checkpoint of the table", tablename)
+		return cp, err
+	}
+	return cp, tx.Commit()
+}
+
+//resumeColumns returns the expressions selecting the columns of the resume table from the table.
+//If the schema is to be evolved, the columns to be added are selected as nulls and the columns to be widened are cast to the widened types
+func resumeColumns(tx *sql.Tx, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions) ([]string, error) {
+	colNames := columnNames(columns, "")
+	cols := make([]string, len(colNames))
+	for i, name := range colNames {
+		cols[i] = `"` + name + `"`
+	}
+	if !opts.EvolveSchema || !opts.AppendData {
+		return cols, nil
+	}
+	existing, err := columnTypes(tx, tablename)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := toolkit.PlanSchemaEvolution(existing, columns)
+	if err != nil {
+		return nil, err
+	}
+	exprs := map[string]string{}
+	for _, c := range changes {
+		switch c.Kind {
+		case toolkit.SchemaChangeAddColumn:
+			exprs[c.Column] = fmt.Sprintf(`NULL::%s AS "%s"`, convertToPostgresDataType(c.To, true), c.Column)
+		case toolkit.SchemaChangeWidenColumn:
+			exprs[c.Column] = fmt.Sprintf(`"%s"::%s AS "%s"`, c.Column, convertToPostgresDataType(c.To, false), c.Column)
+		}
+	}
+	for i, name := range colNames {
+		if expr, ok := exprs[name]; ok {
+			cols[i] = expr
+		}
+	}
+	return cols, nil
+}
+
+//commitChunk copies the next chunk of rows to the resume table and commits it along with the checkpoint.
+//The dates of the date columns are parsed with their date format like in a single dump.
+//The invalid rows in the chunk are loaded to the reject table as per the validation mode. The checkpoint is updated once committed
+func (p Postgres) commitChunk(ctx context.Context, chunks *toolkit.ChunkReader, cp *checkpoint, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, tracker *toolkit.ProgressTracker, logger log.Log) (toolkit.DumpResult, error) {
+	/*
+	 * We will start a transaction for the db operation
+	 * Then we will copy the rows in the chunk to the resume table
+	 *		the dates of the date columns are copied as text and parsed with their date format
+	 * If required we will load the invalid rows to the reject table
+	 * Then we will update the checkpoint and commit the changes
+	 */
+	//starting the db transaction
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return toolkit.DumpResult{}, err
+	}
+	defer tx.Rollback()
+
+	//copying the rows in the chunk
+	resumeTable := toolkit.ResumeTableName(tablename)
+	colNames := columnNames(columns, "")
+	loadTable := resumeTable
+	var rows toolkit.RowReader = chunks
+	dates, err := dateColumns(tx, resumeTable, columns)
+	if err != nil {
+		logger.Error("error while getting the date columns of the table", resumeTable)
+		return toolkit.DumpResult{}, err
+	}
+	if len(dates) != 0 {
+		loadTable, err = createDatesTable(tx, resumeTable, colNames, dates, logger)
+		if err != nil {
+			return toolkit.DumpResult{}, err
+		}
+		rows = newDateRowReader(rows, columns, dates)
+	}
+	result, rejectFilename, err := copyRows(tx, loadTable, colNames, columns, rows, opts, tracker)
+	if len(rejectFilename) != 0 {
+		defer os.Remove(rejectFilename)
+	}
+	if err != nil {
+		return result, err
+	}
+	if len(dates) != 0 {
+		if err := insertParsedDates(tx, loadTable, resumeTable, colNames, dates, logger); err != nil {
+			return result, err
+		}
+	}
+
+	//loading the invalid rows to the reject table
+	//the reject table is recreated only with the first chunk when the data is not appended
+	if len(rejectFilename) != 0 && result.RowsRejected > 0 {
+		rejectTable := rejectTableName(tablename, opts)
+		err = loadRejectedRows(tx, rejectFilename, rejectTable, columns, opts.AppendData || cp.chunk > 0)
+		if err != nil {
+			logger.Error("error while loading the invalid rows to the reject table", rejectTable)
+			return result, err
+		}
+	}
+
+	//updating the checkpoint
+	next := checkpoint{
+		chunk:        cp.chunk + 1,
+		rowsRead:     cp.rowsRead + chunks.Rows(),
+		rowsLoaded:   cp.rowsLoaded + result.RowsLoaded,
+		rowsRejected: cp.rowsRejected + result.RowsRejected,
+	}
+	_, err = tx.Exec(`UPDATE "`+toolkit.CheckpointTableName+`" SET chunk = $2, rows_read = $3, rows_loaded = $4, rows_rejected = $5, updated_at = now() WHERE table_name = $1`,
+		tablename, next.chunk, next.rowsRead, next.rowsLoaded, next.rowsRejected)
+	if err != nil {
+		logger.Error("error while updating the checkpoint of the table", tablename)
+		return result, err
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	*cp = next
+	logger.Info("committed the chunk", cp.chunk, "to the resume table of", tablename, "total no. of rows copied:-", cp.rowsLoaded)
+	return result, nil
+}
+
+//finishCheckpoint moves the rows in the resume table to the table as per the dump options in a single transaction.
+//The schema of the table is evolved if required and the resume table and the checkpoint are removed along with it
+func (p Postgres) finishCheckpoint(ctx context.Context, tablename string, columns []interpreter.ColumnNode, cp checkpoint, opts toolkit.DumpOptions, result *toolkit.DumpResult, logger log.Log) error {
+	/*
+	 * We will start a transaction for the db operation
+	 * Then we will create or truncate the table if required
+	 * If required we will evolve the schema of the table
+	 * Then we will move the rows to the table
+	 *		while merging, the resume table is merged like a staging table
+	 *		while replacing with a shadow table, the rows are moved to the shadow table which is swapped with the table
+	 * Then we will remove the resume table and the checkpoint
+	 */
+	//starting the db transaction
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//creating the table
+	err = prepareTable(tx, tablename, columns, "", opts, logger)
+	if err != nil {
+		return err
+	}
+
+	//evolving the schema of the table for the appended data
+	result.SchemaChanges, err = evolveTable(tx, tablename, columns, opts, logger)
+	if err != nil {
+		return err
+	}
+
+	//moving the rows
+	resumeTable := toolkit.ResumeTableName(tablename)
+	colNames := columnNames(columns, "")
+	cols := quoteNames(colNames)
+	logger.Info("moving the rows from the resume table", resumeTable, "to the table", tablename)
+	switch {
+	case len(opts.MergeKeys) != 0:
+		err = mergeStagingTable(tx, resumeTable, tablename, colNames, opts, result, logger)
+	case shadowReplace(opts):
+		var shadowTable string
+		shadowTable, err = createShadowTable(tx, tablename, logger)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf(`INSERT INTO "%s" (%s) SELECT %s FROM "%s"`, shadowTable, cols, cols, resumeTable))
+		if err != nil {
+			return err
+		}
+		//the rows committed in the chunks are expected in the shadow table unless the dump options expect otherwise
+		expectedRows := cp.rowsLoaded
+		if opts.ExpectedRows > 0 {
+			expectedRows = opts.ExpectedRows
+		}
+		err = swapShadowTable(tx, shadowTable, tablename, expectedRows, logger)
+	default:
+		_, err = tx.Exec(fmt.Sprintf(`INSERT INTO "%s" (%s) SELECT %s FROM "%s"`, tablename, cols, cols, resumeTable))
+	}
+	if err != nil {
+		return err
+	}
+
+	//removing the resume table and the checkpoint
+	if _, err := tx.Exec(`DROP TABLE "` + resumeTable + `"`); err != nil {
+		logger.Error("error while dropping the resume table", resumeTable)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM "`+toolkit.CheckpointTableName+`" WHERE table_name = $1`, tablename); err != nil {
+		logger.Error("error while removing the checkpoint of the table", tablename)
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+//DeleteTable deletes the table from the datastore. It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+func (p Postgres) DeleteTable(tablename string) error {
+	return p.inTableTx("delete", tablename, func(tx *sql.Tx) error {
+		if err := requireTable(tx, "delete", tablename); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DROP TABLE " + Dialect{}.QuoteIdentifier(tablename))
+		return err
+	})
+}
+
+//DropTableIfExists deletes the table from the datastore if it exists
+func (p Postgres) DropTableIfExists(tablename string) error {
+	_, err := p.DB.Exec("DROP TABLE IF EXISTS " + Dialect{}.QuoteIdentifier(tablename))
+	return TranslateError(err)
+}
+
+//RenameTable renames the table. It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+//and with toolkit.ErrTableExists if a table with the new name already exists
+func (p Postgres) RenameTable(from string, to string) error {
+	return p.inTableTx("rename", from, func(tx *sql.Tx) error {
+		if err := requireTable(tx, "rename", from); err != nil {
+			return err
+		}
+		if err := requireNoTable(tx, "rename", to); err != nil {
+			return err
+		}
+		return renameTable(tx, from, to)
+	})
+}
+
+//CloneTable creates a new table with the same columns, defaults, constraints and indexes as the table.
+//If withData is set, the rows of the table are copied to the new table.
+//It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+//and with toolkit.ErrTableExists if the new table already exists
+func (p Postgres) CloneTable(from string, to string, withData bool) error {
+	return p.inTableTx("clone", from, func(tx *sql.Tx) error {
+		if err := requireTable(tx, "clone", from); err != nil {
+			return err
+		}
+		if err := requireNoTable(tx, "clone", to); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING ALL)`, Dialect{}.QuoteIdentifier(to), Dialect{}.QuoteIdentifier(from))); err != nil {
+			return err
+		}
+		if !withData {
+			return nil
+		}
+		_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, Dialect{}.QuoteIdentifier(to), Dialect{}.QuoteIdentifier(from)))
+		return err
+	})
+}
+
+//TruncateTable removes all the rows in the table. It returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+func (p Postgres) TruncateTable(tablename string) error {
+	return p.inTableTx("truncate", tablename, func(tx *sql.Tx) error {
+		if err := requireTable(tx, "truncate", tablename); err != nil {
+			return err
+		}
+		_, err := tx.Exec(Dialect{}.TruncateTable(tablename))
+		return err
+	})
+}
+
+//inTableTx runs the operation on the table in a transaction. The errors other than the table errors are translated and wrapped as a toolkit.TableError
+func (p Postgres) inTableTx(op string, tablename string, fn func(tx *sql.Tx) error) error {
+	tx, err := p.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return TranslateError(err)
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		if _, ok := err.(*toolkit.TableError); ok {
+			return err
+		}
+		return &toolkit.TableError{Op: op, Table: tablename, Err: TranslateError(err)}
+	}
+	return TranslateError(tx.Commit())
+}
+
+//requireTable returns a toolkit.TableError with toolkit.ErrTableNotFound if the table doesn't exist
+func requireTable(tx *sql.Tx, op string, tablename string) error {
+	exists, err := tableExists(tx, tablename)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &toolkit.TableError{Op: op, Table: tablename, Err: toolkit.ErrTableNotFound}
+	}
+	return nil
+}
+
+//requireNoTable returns a toolkit.TableError with toolkit.ErrTableExists if the table exists
+func requireNoTable(tx *sql.Tx, op string, tablename string) error {
+	exists, err := tableExists(tx, tablename)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &toolkit.TableError{Op: op, Table: tablename, Err: toolkit.ErrTableExists}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package postgres_test
+
+import (
+	"errors"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestTableErrors(t *testing.T) {
+	conn := testDatastore(t)
+	for _, table := range []string{`sales "src"`, "sales_dst", "sales_renamed", "sales_cloned"} {
+		conn.DropTableIfExists(table)
+		defer conn.DropTableIfExists(table)
+	}
+	_, err := conn.DB.Exec(`CREATE TABLE "sales ""src""" (id bigint);` + `INSERT INTO "sales ""src""" VALUES (1);` +
+		`CREATE TABLE sales_dst (id bigint)`)
+	if err != nil {
+		t.Error("error while creating the tables", err)
+		return
+	}
+	cases := []struct {
+		op   string
+		err  error
+		kind error
+	}{
+		{"rename missing", conn.RenameTable("sales_missing", "sales_renamed"), toolkit.ErrTableNotFound},
+		{"rename to existing", conn.RenameTable(`sales "src"`, "sales_dst"), toolkit.ErrTableExists},
+		{"clone missing", conn.CloneTable("sales_missing", "sales_cloned", true), toolkit.ErrTableNotFound},
+		{"clone to existing", conn.CloneTable(`sales "src"`, "sales_dst", true), toolkit.ErrTableExists},
+		{"truncate missing", conn.TruncateTable("sales_missing"), toolkit.ErrTableNotFound},
+		{"delete missing", conn.DeleteTable("sales_missing"), toolkit.ErrTableNotFound},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, c.kind) {
+			t.Error("expected", c.op, "to fail with", c.kind, "got", c.err)
+			return
+		}
+	}
+	if err := conn.DropTableIfExists("sales_missing"); err != nil {
+		t.Error("expected dropping a missing table if exists to succeed. got", err)
+		return
+	}
+
+	//the tables with quotes in their names are cloned, truncated and renamed
+	if err := conn.CloneTable(`sales "src"`, "sales_cloned", true); err != nil {
+		t.Error("error while cloning the table", err)
+		return
+	}
+	if err := conn.TruncateTable(`sales "src"`); err != nil {
+		t.Error("error while truncating the table", err)
+		return
+	}
+	if err := conn.RenameTable(`sales "src"`, "sales_renamed"); err != nil {
+		t.Error("error while renaming the table", err)
+		return
+	}
+	count := 0
+	if err := conn.DB.QueryRow(`SELECT COUNT(*) FROM sales_cloned`).Scan(&count); err != nil || count != 1 {
+		t.Error("expected the cloned table to have the row of the table. got", count, err)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package retry has a datastore retrying the operations on another datastore failing with the transient errors
+package retry
+
+import (
+	"context"
+	"io"
+
+	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//Datastore wraps a datastore retrying the operations failing with the transient errors as per the retry policy.
+//Idempotent operations like the read only queries and replacing or merging the data are retried for all the transient errors.
+//The rest like the queries changing the data and appending the data are retried only when the error guarantees that they weren't applied,
+//so that they are never applied twice. Dumps reading from a reader and the exports writing to a writer are never retried
+//as the reader is consumed and the writer is written by the failed attempt.
+//The operations of the wrapped datastore are expected to translate their errors to the errors of the toolkit
+type Datastore struct {
+	toolkit.Datastore
+	policy toolkit.RetryPolicy
+}
+
+//NewDatastore returns a datastore retrying the operations on the given datastore as per the retry policy
+func NewDatastore(d toolkit.Datastore, policy toolkit.RetryPolicy) *Datastore {
+	return &Datastore{Datastore: d, policy: policy}
+}
+
+//do runs the operation retrying it as per the retry policy
+func (d *Datastore) do(ctx context.Context, idempotent bool, op func() error) error {
+	return d.policy.Do(ctx, idempotent, op)
+}
+
+//idempotentDump returns true if dumping the data as per the dump options leaves the table in the same state when repeated.
+//Replacing the data and merging it using the merge keys are idempotent, appending it isn't.
+//Creating the table isn't either as the table created by a failed attempt fails the next one unless the data is replaced using a shadow table
+func idempotentDump(opts toolkit.DumpOptions) bool {
+	return (!opts.AppendData || len(opts.MergeKeys) != 0) && (!opts.CreateTable || opts.ShadowReplace)
+}
+
+//Exec runs the query retrying it for all the transient errors if it is read only
+func (d *Datastore) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := d.do(nil, toolkit.ReadOnlyQuery(query), func() error {
+		var err error
+		results, err = d.Datastore.Exec(query, args...)
+		return err
+	})
+	return results, err
+}
+
+//DumpCSV dumps the csv file retrying it for all the transient errors unless the data is appended or the table is created
+func (d *Datastore) DumpCSV(filename string, tablename string, columns []interpreter.ColumnNode, appendData bool, createTable bool, doScp bool, logger log.Log) error {
+	return d.do(nil, idempotentDump(toolkit.DumpOptions{AppendData: appendData, CreateTable: createTable}), func() error {
+		return d.Datastore.DumpCSV(filename, tablename, columns, appendData, createTable, doScp, logger)
+	})
+}
+
+//DumpCSVWithOptions dumps the csv file retrying it for all the transient errors unless the data is appended or the table is created
+func (d *Datastore) DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	var result toolkit.DumpResult
+	err := d.do(opts.Context, idempotentDump(opts), func() error {
+		var err error
+		result, err = d.Datastore.DumpCSVWithOptions(filename, tablename, columns, opts, logger)
+		return err
+	})
+	return result, err
+}
+
+//DumpCSVArchive dumps the csv files in the archive retrying it for all the transient errors unless the data is appended or the table is created
+func (d *Datastore) DumpCSVArchive(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	var results map[string]toolkit.DumpResult
+	err := d.do(opts.Context, idempotentDump(opts), func() error {
+		var err error
+		results, err = d.Datastore.DumpCSVArchive(filename, tablename, columns, opts, logger)
+		return err
+	})
+	return results, err
+}
+
+//DumpCSVResumable dumps the csv file in chunks retrying it for all the transient errors unless the data is appended or the table is created.
+//A retry resumes the dump from the last committed chunk, but the appended chunks are appended again
+//if the dump failed after they were moved to the table
+func (d *Datastore) DumpCSVResumable(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	var result toolkit.DumpResult
+	err := d.do(opts.Context, idempotentDump(opts), func() error {
+		var err error
+		result, err = d.Datastore.DumpCSVResumable(filename, tablename, columns, opts, logger)
+		return err
+	})
+	return result, err
+}
+
+//DumpJSONL dumps the json lines file retrying it for all the transient errors unless the data is appended or the table is created
+func (d *Datastore) DumpJSONL(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	var result toolkit.DumpResult
+	err := d.do(opts.Context, idempotentDump(opts), func() error {
+		var err error
+		result, err = d.Datastore.DumpJSONL(filename, tablename, columns, opts, logger)
+		return err
+	})
+	return result, err
+}
+
+//DumpParquet dumps the parquet file retrying it for all the transient errors unless the data is appended or the table is created
+func (d *Datastore) DumpParquet(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	var result toolkit.DumpResult
+	err := d.do(opts.Context, idempotentDump(opts), func() error {
+		var err error
+		result, err = d.Datastore.DumpParquet(filename, tablename, columns, opts, logger)
+		return err
+	})
+	return result, err
+}
+
+//DumpExcel dumps the excel workbook retrying it for all the transient errors unless the data is appended or the table is created
+func (d *Datastore) DumpExcel(filename string, tablename string, columns []interpreter.ColumnNode, excel toolkit.ExcelOptions, opts toolkit.DumpOptions, logger log.Log) (map[string]toolkit.DumpResult, error) {
+	var results map[string]toolkit.DumpResult
+	err := d.do(opts.Context, idempotentDump(opts), func() error {
+		var err error
+		results, err = d.Datastore.DumpExcel(filename, tablename, columns, excel, opts, logger)
+		return err
+	})
+	return results, err
+}
+
+//ExportCSV exports the table without retrying as the writer is written by the failed attempt
+func (d *Datastore) ExportCSV(w io.Writer, tablename string, opts toolkit.ExportOptions) error {
+	return d.Datastore.ExportCSV(w, tablename, opts)
+}
+
+//DropPreviousTable drops the previous version of the table retrying it for all the transient errors
+func (d *Datastore) DropPreviousTable(tablename string) error {
+	return d.do(nil, true, func() error {
+		return d.Datastore.DropPreviousTable(tablename)
+	})
+}
+
+//DropTableIfExists deletes the table if exists retrying it for all the transient errors
+func (d *Datastore) DropTableIfExists(tablename string) error {
+	return d.do(nil, true, func() error {
+		return d.Datastore.DropTableIfExists(tablename)
+	})
+}
+
+//TruncateTable removes all the rows in the table retrying it for all the transient errors
+func (d *Datastore) TruncateTable(tablename string) error {
+	return d.do(nil, true, func() error {
+		return d.Datastore.TruncateTable(tablename)
+	})
+}
+
+//ReorderColumns reorders the columns of the table retrying it for all the transient errors
+func (d *Datastore) ReorderColumns(tablename string, colNames []string) error {
+	return d.do(nil, true, func() error {
+		return d.Datastore.ReorderColumns(tablename, colNames)
+	})
+}
+
+//CreateAutoIndex creates the index if not exists retrying it for all the transient errors
+func (d *Datastore) CreateAutoIndex(tablename string, colName string, method string) (string, error) {
+	var name string
+	err := d.do(nil, true, func() error {
+		var err error
+		name, err = d.Datastore.CreateAutoIndex(tablename, colName, method)
+		return err
+	})
+	return name, err
+}
+
+//DropIndex drops the index if exists retrying it for all the transient errors
+func (d *Datastore) DropIndex(indexname string) error {
+	return d.do(nil, true, func() error {
+		return d.Datastore.DropIndex(indexname)
+	})
+}
+
+//ListTables lists the tables retrying it for all the transient errors
+func (d *Datastore) ListTables() ([]toolkit.TableInfo, error) {
+	var tables []toolkit.TableInfo
+	err := d.do(nil, true, func() error {
+		var err error
+		tables, err = d.Datastore.ListTables()
+		return err
+	})
+	return tables, err
+}
+
+//TableExists checks whether the table exists retrying it for all the transient errors
+func (d *Datastore) TableExists(tablename string) (bool, error) {
+	var exists bool
+	err := d.do(nil, true, func() error {
+		var err error
+		exists, err = d.Datastore.TableExists(tablename)
+		return err
+	})
+	return exists, err
+}
+
+//DescribeTable describes the table retrying it for all the transient errors
+func (d *Datastore) DescribeTable(tablename string) (toolkit.TableDescription, error) {
+	var desc toolkit.TableDescription
+	err := d.do(nil, true, func() error {
+		var err error
+		desc, err = d.Datastore.DescribeTable(tablename)
+		return err
+	})
+	return desc, err
+}
+
+//GetColumnTypes returns the column types of the table retrying it for all the transient errors
+func (d *Datastore) GetColumnTypes(tableName string) ([]toolkit.Column, error) {
+	var columns []toolkit.Column
+	err := d.do(nil, true, func() error {
+		var err error
+		columns, err = d.Datastore.GetColumnTypes(tableName)
+		return err
+	})
+	return columns, err
+}
+
+//DeleteTable deletes the table retrying it only when it wasn't applied
+func (d *Datastore) DeleteTable(tablename string) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.DeleteTable(tablename)
+	})
+}
+
+//RenameTable renames the table retrying it only when it wasn't applied
+func (d *Datastore) RenameTable(from string, to string) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.RenameTable(from, to)
+	})
+}
+
+//CloneTable clones the table retrying it only when it wasn't applied
+func (d *Datastore) CloneTable(from string, to string, withData bool) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.CloneTable(from, to, withData)
+	})
+}
+
+//RestorePreviousTable restores the previous version of the table retrying it only when it wasn't applied
+func (d *Datastore) RestorePreviousTable(tablename string) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.RestorePreviousTable(tablename)
+	})
+}
+
+//AddColumn adds the column retrying it only when it wasn't applied
+func (d *Datastore) AddColumn(tablename string, column interpreter.ColumnNode) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.AddColumn(tablename, column)
+	})
+}
+
+//DropColumn drops the column retrying it only when it wasn't applied
+func (d *Datastore) DropColumn(tablename string, colName string) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.DropColumn(tablename, colName)
+	})
+}
+
+//RenameColumn renames the column retrying it only when it wasn't applied
+func (d *Datastore) RenameColumn(tablename string, from string, to string) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.RenameColumn(tablename, from, to)
+	})
+}
+
+//ChangeColumnTypeToDate changes the data type of the column retrying it only when it wasn't applied
+func (d *Datastore) ChangeColumnTypeToDate(tableName string, colName string, dateFormat string) error {
+	return d.do(nil, false, func() error {
+		return d.Datastore.ChangeColumnTypeToDate(tableName, colName, dateFormat)
+	})
+}
+
+//ChangeColumnTypeToDateWithRejects changes the data type of the column retrying it only when it wasn't applied
+func (d *Datastore) ChangeColumnTypeToDateWithRejects(tableName string, colName string, dateFormat string, rejectTable string) (int64, error) {
+	var rejected int64
+	err := d.do(nil, false, func() error {
+		var err error
+		rejected, err = d.Datastore.ChangeColumnTypeToDateWithRejects(tableName, colName, dateFormat, rejectTable)
+		return err
+	})
+	return rejected, err
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cuttle-ai/brain/log"
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/retry"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//flakyDatastore loses the connection on every operation
+type flakyDatastore struct {
+	toolkit.Datastore
+	calls int
+}
+
+func (f *flakyDatastore) DumpCSVWithOptions(filename string, tablename string, columns []interpreter.ColumnNode, opts toolkit.DumpOptions, logger log.Log) (toolkit.DumpResult, error) {
+	f.calls++
+	return toolkit.DumpResult{}, &toolkit.DatastoreError{Kind: toolkit.ErrConnection}
+}
+
+func (f *flakyDatastore) Exec(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	f.calls++
+	return nil, &toolkit.DatastoreError{Kind: toolkit.ErrConnection}
+}
+
+func TestDatastore(t *testing.T) {
+	policy := toolkit.RetryPolicy{MaxAttempts: 3, Sleep: func(ctx context.Context, d time.Duration) error { return nil }}
+	cases := []struct {
+		name  string
+		run   func(d *retry.Datastore) error
+		calls int
+	}{
+		{"replace dump", func(d *retry.Datastore) error {
+			_, err := d.DumpCSVWithOptions("sales.csv", "sales", nil, toolkit.DumpOptions{}, nil)
+			return err
+		}, 3},
+		{"append dump", func(d *retry.Datastore) error {
+			_, err := d.DumpCSVWithOptions("sales.csv", "sales", nil, toolkit.DumpOptions{AppendData: true}, nil)
+			return err
+		}, 1},
+		{"merge dump", func(d *retry.Datastore) error {
+			_, err := d.DumpCSVWithOptions("sales.csv", "sales", nil, toolkit.DumpOptions{AppendData: true, MergeKeys: []string{"id"}}, nil)
+			return err
+		}, 3},
+		{"create table dump", func(d *retry.Datastore) error {
+			_, err := d.DumpCSVWithOptions("sales.csv", "sales", nil, toolkit.DumpOptions{CreateTable: true}, nil)
+			return err
+		}, 1},
+		{"create table shadow replace dump", func(d *retry.Datastore) error {
+			_, err := d.DumpCSVWithOptions("sales.csv", "sales", nil, toolkit.DumpOptions{CreateTable: true, ShadowReplace: true}, nil)
+			return err
+		}, 3},
+		{"read only query", func(d *retry.Datastore) error {
+			_, err := d.Exec("SELECT count(*) FROM sales")
+			return err
+		}, 3},
+		{"insert query", func(d *retry.Datastore) error {
+			_, err := d.Exec("INSERT INTO sales VALUES (1)")
+			return err
+		}, 1},
+	}
+	for _, c := range cases {
+		f := &flakyDatastore{}
+		if err := c.run(retry.NewDatastore(f, policy)); err == nil {
+			t.Error(c.name, "expected the connection error")
+			return
+		}
+		if f.calls != c.calls {
+			t.Error(c.name, "expected", c.calls, "attempts. got", f.calls)
+			return
+		}
+	}
+}
@@ -7,10 +7,12 @@ package services
 
 import (
 	"errors"
+	"os"
 	"strconv"
 
 	toolkit "github.com/cuttle-ai/db-toolkit"
 	"github.com/cuttle-ai/db-toolkit/datastores/postgres"
+	"github.com/cuttle-ai/db-toolkit/datastores/transfer"
 	"github.com/jinzhu/gorm"
 )
 
@@ -19,6 +21,15 @@ const (
 	POSTGRES = "POSTGRES"
 )
 
+const (
+	//LOCAL represents the transfer of the data files to a data directory in the local file system
+	LOCAL = "LOCAL"
+	//SFTP represents the transfer of the data files to a data directory in a remote server using sftp
+	SFTP = "SFTP"
+	//SSH represents the transfer of the data files to a data directory in a remote server using the ssh command of the system
+	SSH = "SSH"
+)
+
 //Service is defnition of the datastore service
 type Service struct {
 	gorm.Model
@@ -40,6 +51,19 @@ type Service struct {
 	DatastoreType string
 	//DataDirectory is the directory where the data is stored
 	DataDirectory string
+	//TransferType is the type of transfer like LOCAL, SFTP or SSH used for staging the data files in the data directory.
+	//If empty, LOCAL is used for the local data directories and SSH for the remote ones
+	TransferType string
+	//TransferPasswordEnv is the name of the environment variable having the password used for the sftp transfer.
+	//The password itself isn't stored with the service
+	TransferPasswordEnv string
+	//TransferKeyFile is the private key file used for the sftp transfer
+	TransferKeyFile string
+	//TransferKnownHostsFile is the known hosts file used for verifying the host key of the remote server while transferring the data files.
+	//It is required for SFTP. For SSH, the known hosts files in the ssh configuration of the user are used if not set
+	TransferKnownHostsFile string
+	//Observer if set is reported the operations run on the datastore of the service. It isn't stored with the service
+	Observer toolkit.Observer `gorm:"-" json:"-"`
 }
 
 //GetAll returns the list of datastore available
@@ -84,6 +108,18 @@ func (s Service) Validate() error {
 	if len(s.DataDirectory) == 0 {
 		return errors.New("Data Directory can't be empty")
 	}
+	if _, err := transfer.ParseStagingPath(s.DataDirectory); err != nil {
+		return errors.New("Data Directory is invalid. " + err.Error())
+	}
+	if len(s.TransferType) != 0 && s.TransferType != LOCAL && s.TransferType != SFTP && s.TransferType != SSH {
+		return errors.New("Transfer Type should be one of " + LOCAL + ", " + SFTP + " or " + SSH + " got " + s.TransferType)
+	}
+	if s.TransferType == SFTP && len(s.TransferPasswordEnv) == 0 && len(s.TransferKeyFile) == 0 {
+		return errors.New("Transfer Password Env or Transfer Key File is required for " + SFTP)
+	}
+	if s.TransferType == SFTP && len(s.TransferKnownHostsFile) == 0 {
+		return errors.New("Transfer Known Hosts File is required for " + SFTP)
+	}
 	return nil
 }
 
@@ -95,14 +131,18 @@ func (s *Service) Create(conn *gorm.DB) error {
 //Update will update a given service
 func (s *Service) Update(conn *gorm.DB) error {
 	return conn.Model(s).Updates(map[string]interface{}{
-		"url":            s.URL,
-		"port":           s.Port,
-		"username":       s.Username,
-		"password":       s.Password,
-		"name":           s.Name,
-		"group":          s.Group,
-		"datastore_type": s.DatastoreType,
-		"data_directory": s.DataDirectory,
+		"url":                       s.URL,
+		"port":                      s.Port,
+		"username":                  s.Username,
+		"password":                  s.Password,
+		"name":                      s.Name,
+		"group":                     s.Group,
+		"datastore_type":            s.DatastoreType,
+		"data_directory":            s.DataDirectory,
+		"transfer_type":             s.TransferType,
+		"transfer_password_env":     s.TransferPasswordEnv,
+		"transfer_key_file":         s.TransferKeyFile,
+		"transfer_known_hosts_file": s.TransferKnownHostsFile,
 	}).Error
 }
 
@@ -133,7 +173,41 @@ func (s Service) Datastore() (toolkit.Datastore, error) {
 			//error while creating a postgres connection
 			return nil, err
 		}
+		ps.Transport, err = s.Transport()
+		if err != nil {
+			//error while creating the transport for the data files
+			return nil, err
+		}
+		ps.Observer = s.Observer
 		return ps, nil
 	}
 	return nil, errors.New("couldn't identify the type of service " + s.DatastoreType)
 }
+
+//Transport returns the transport for staging the data files in the data directory of the service as per its transfer type
+func (s Service) Transport() (transfer.Transport, error) {
+	staging, err := transfer.ParseStagingPath(s.DataDirectory)
+	if err != nil {
+		return nil, err
+	}
+	transferType := s.TransferType
+	if len(transferType) == 0 {
+		transferType = SSH
+		if staging.IsLocal() {
+			transferType = LOCAL
+		}
+	}
+	switch transferType {
+	case LOCAL:
+		return transfer.Local{}, nil
+	case SFTP:
+		config := transfer.SFTPConfig{KeyFile: s.TransferKeyFile, KnownHostsFile: s.TransferKnownHostsFile}
+		if len(s.TransferPasswordEnv) != 0 {
+			config.Password = os.Getenv(s.TransferPasswordEnv)
+		}
+		return transfer.NewSFTP(staging, config)
+	case SSH:
+		return transfer.Command{Staging: staging, KnownHostsFile: s.TransferKnownHostsFile}, nil
+	}
+	return nil, errors.New("couldn't identify the transfer type " + s.TransferType)
+}
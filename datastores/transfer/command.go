@@ -0,0 +1,57 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package transfer
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+//Command transfers the data files to a staging directory in a remote server using the ssh command of the system.
+//It uses the ssh configuration and the keys of the user running the process. The host key of the server is always verified
+//and the transfer fails if the server isn't in the known hosts
+type Command struct {
+	//Staging is the staging directory in the remote server
+	Staging StagingPath
+	//KnownHostsFile if set is the known hosts file used for verifying the host key of the server.
+	//Else the known hosts files in the ssh configuration of the user are used
+	KnownHostsFile string
+}
+
+//Put writes the content read from r to the file at the given path in the remote server
+func (c Command) Put(ctx context.Context, r io.Reader, filePath string) error {
+	cmd := exec.CommandContext(ctx, "ssh", c.args("cat > "+ShellQuote(filePath))...)
+	cmd.Stdin = r
+	return cmd.Run()
+}
+
+//Remove removes the file at the given path in the remote server
+func (c Command) Remove(filePath string) error {
+	return exec.Command("ssh", c.args("rm -- "+ShellQuote(filePath))...).Run()
+}
+
+//ShellQuote quotes the value as a single argument for the shell.
+//The value is single quoted with the single quotes in it escaped so that the remote shell never interprets it
+func ShellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+//args returns the arguments to the ssh command for running the given command in the remote server
+func (c Command) args(command string) []string {
+	args := []string{"-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=yes"}
+	if len(c.KnownHostsFile) != 0 {
+		args = append(args, "-o", "UserKnownHostsFile="+c.KnownHostsFile)
+	}
+	if len(c.Staging.Port) != 0 {
+		args = append(args, "-p", c.Staging.Port)
+	}
+	host := c.Staging.Host
+	if len(c.Staging.User) != 0 {
+		host = c.Staging.User + "@" + host
+	}
+	return append(args, host, command)
+}
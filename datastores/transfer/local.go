@@ -0,0 +1,49 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package transfer
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+//Local transfers the data files to a staging directory in the local file system.
+//It can be used when the datastore is running in the same server or the staging directory is a shared mount
+type Local struct{}
+
+//Put writes the content read from r to the file at the given path
+func (l Local) Put(ctx context.Context, r io.Reader, filePath string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, contextReader{ctx: ctx, r: r})
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+	}
+	return err
+}
+
+//Remove removes the file at the given path
+func (l Local) Remove(filePath string) error {
+	return os.Remove(filePath)
+}
+
+//contextReader is a reader whose reads fail once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
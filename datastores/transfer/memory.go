@@ -0,0 +1,68 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+//Memory keeps the transferred files in the memory. It is meant to be used as a fake transport in the tests
+type Memory struct {
+	m     sync.Mutex
+	files map[string][]byte
+}
+
+//NewMemory returns a new in memory transport
+func NewMemory() *Memory {
+	return &Memory{files: map[string][]byte{}}
+}
+
+//Put keeps the content read from r in the memory as the file at the given path
+func (m *Memory) Put(ctx context.Context, r io.Reader, filePath string) error {
+	b, err := ioutil.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return err
+	}
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.files[filePath] = b
+	return nil
+}
+
+//Remove removes the file at the given path from the memory
+func (m *Memory) Remove(filePath string) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+	if _, ok := m.files[filePath]; !ok {
+		return errors.New("couldn't find the file " + filePath)
+	}
+	delete(m.files, filePath)
+	return nil
+}
+
+//File returns the content of the file at the given path. It returns false if the file doesn't exist
+func (m *Memory) File(filePath string) ([]byte, bool) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	b, ok := m.files[filePath]
+	return b, ok
+}
+
+//Files returns the paths of the files in the memory in the sorted order
+func (m *Memory) Files() []string {
+	m.m.Lock()
+	defer m.m.Unlock()
+	paths := make([]string, 0, len(m.files))
+	for p := range m.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
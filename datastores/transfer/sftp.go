@@ -0,0 +1,156 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+//SFTPConfig has the credentials for connecting to the remote server having the staging directory
+type SFTPConfig struct {
+	//Password is the password of the user. Either the password or the key file is required
+	Password string
+	//KeyFile is the path of the private key file of the user
+	KeyFile string
+	//KnownHostsFile is the known hosts file used for verifying the host key of the server. It is required
+	//so that the credentials are never sent to a server that isn't verified
+	KnownHostsFile string
+	//Timeout is the timeout for connecting to the server. Defaults to 30 seconds
+	Timeout time.Duration
+}
+
+//SFTP transfers the data files to a staging directory in a remote server using sftp
+type SFTP struct {
+	//Staging is the staging directory in the remote server
+	Staging StagingPath
+	//Config has the credentials for connecting to the server
+	Config SFTPConfig
+}
+
+//NewSFTP returns the sftp transport for the staging directory in a remote server
+func NewSFTP(staging StagingPath, config SFTPConfig) (*SFTP, error) {
+	if staging.IsLocal() {
+		return nil, errors.New("expected the staging path for sftp to be in a remote server. got " + staging.String())
+	}
+	if len(staging.User) == 0 {
+		return nil, errors.New("expected the staging path for sftp to have the user like user@myserver.com:/home/user/directory")
+	}
+	if len(config.Password) == 0 && len(config.KeyFile) == 0 {
+		return nil, errors.New("either the password or the key file is required for sftp")
+	}
+	if len(config.KnownHostsFile) == 0 {
+		return nil, errors.New("the known hosts file is required for verifying the host key of the server for sftp")
+	}
+	return &SFTP{Staging: staging, Config: config}, nil
+}
+
+//Put writes the content read from r to the file at the given path in the remote server
+func (s *SFTP) Put(ctx context.Context, r io.Reader, filePath string) error {
+	conn, client, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer client.Close()
+
+	//the connection is closed once the context is done to abort the transfer
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	f, err := client.Create(filePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, contextReader{ctx: ctx, r: r})
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		client.Remove(filePath)
+	}
+	return err
+}
+
+//Remove removes the file at the given path in the remote server
+func (s *SFTP) Remove(filePath string) error {
+	conn, client, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer client.Close()
+	return client.Remove(filePath)
+}
+
+//connect connects to the remote server and starts the sftp session
+func (s *SFTP) connect() (*ssh.Client, *sftp.Client, error) {
+	/*
+	 * We will get the auth methods
+	 * Then we will get the host key callback
+	 * Then we will connect to the server and start the sftp session
+	 */
+	//getting the auth methods
+	auths := []ssh.AuthMethod{}
+	if len(s.Config.KeyFile) != 0 {
+		key, err := ioutil.ReadFile(s.Config.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if len(s.Config.Password) != 0 {
+		auths = append(auths, ssh.Password(s.Config.Password))
+	}
+
+	//getting the host key callback
+	if len(s.Config.KnownHostsFile) == 0 {
+		return nil, nil, errors.New("the known hosts file is required for verifying the host key of the server for sftp")
+	}
+	hostKeyCallback, err := knownhosts.New(os.ExpandEnv(s.Config.KnownHostsFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	timeout := s.Config.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	//connecting to the server
+	conn, err := ssh.Dial("tcp", s.Staging.Address(), &ssh.ClientConfig{
+		User:            s.Staging.User,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, client, nil
+}
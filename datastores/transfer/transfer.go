@@ -0,0 +1,130 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package transfer has the transports for staging the data files in the directory from which a datastore loads them
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	//SchemeFile is the scheme of the staging directory in the local file system
+	SchemeFile = "file"
+	//SchemeSFTP is the scheme of the staging directory in a remote server accessible over ssh
+	SchemeSFTP = "sftp"
+)
+
+//Transport transfers the data files to the staging directory of a datastore
+type Transport interface {
+	//Put writes the content read from r to the file at the given path in the staging directory.
+	//The transfer is aborted once the context is done
+	Put(ctx context.Context, r io.Reader, filePath string) error
+	//Remove removes the file at the given path in the staging directory
+	Remove(filePath string) error
+}
+
+//StagingPath is the parsed location of the staging directory of a datastore.
+//It can be a local path like /home/user/data, a scp style remote path like user@myserver.com:/home/user/data
+//or an url like sftp://user@myserver.com:22/home/user/data or file:///home/user/data
+type StagingPath struct {
+	//Scheme is either SchemeFile or SchemeSFTP
+	Scheme string
+	//User is the user for connecting to the remote server
+	User string
+	//Host is the remote server
+	Host string
+	//Port is the ssh port of the remote server. It is empty if not specified
+	Port string
+	//Dir is the path of the staging directory in the server
+	Dir string
+}
+
+//ParseStagingPath parses the location of the staging directory
+func ParseStagingPath(location string) (StagingPath, error) {
+	/*
+	 * We will parse the urls
+	 * Then we will parse the scp style remote paths
+	 * Rest of them are local paths
+	 */
+	if len(location) == 0 {
+		return StagingPath{}, errors.New("staging path can't be empty")
+	}
+
+	//parsing the urls
+	if strings.Contains(location, "://") {
+		u, err := url.Parse(location)
+		if err != nil {
+			return StagingPath{}, err
+		}
+		s := StagingPath{Scheme: u.Scheme, Host: u.Hostname(), Port: u.Port(), Dir: u.Path}
+		if u.User != nil {
+			s.User = u.User.Username()
+		}
+		if s.Scheme != SchemeFile && s.Scheme != SchemeSFTP {
+			return s, errors.New("expected the staging path to have the scheme " + SchemeFile + " or " + SchemeSFTP + ". got " + s.Scheme)
+		}
+		if s.Scheme == SchemeSFTP && len(s.Host) == 0 {
+			return s, errors.New("expected the staging path " + location + " to have the host")
+		}
+		if len(s.Dir) == 0 {
+			return s, errors.New("expected the staging path " + location + " to have the directory")
+		}
+		return s, nil
+	}
+
+	//parsing the scp style remote paths
+	//a colon before the first slash separates the server from the directory like user@myserver.com:/home/user
+	colon := strings.Index(location, ":")
+	slash := strings.Index(location, "/")
+	if colon > 0 && (slash < 0 || colon < slash) {
+		s := StagingPath{Scheme: SchemeSFTP, Host: location[:colon], Dir: location[colon+1:]}
+		if at := strings.LastIndex(s.Host, "@"); at >= 0 {
+			s.User = s.Host[:at]
+			s.Host = s.Host[at+1:]
+		}
+		if len(s.Host) == 0 || len(s.Dir) == 0 {
+			return s, errors.New("expected the staging path " + location + " to be like user@myserver.com:/home/user/directory")
+		}
+		return s, nil
+	}
+
+	return StagingPath{Scheme: SchemeFile, Dir: location}, nil
+}
+
+//IsLocal returns true if the staging directory is in the local file system
+func (s StagingPath) IsLocal() bool {
+	return s.Scheme == SchemeFile
+}
+
+//Join returns the path of the file with the given name in the staging directory
+func (s StagingPath) Join(name string) string {
+	return path.Join(s.Dir, name)
+}
+
+//Address returns the address of the remote server as host:port. The port defaults to 22
+func (s StagingPath) Address() string {
+	port := s.Port
+	if len(port) == 0 {
+		port = "22"
+	}
+	return s.Host + ":" + port
+}
+
+//String returns the staging path in the scp style for the remote servers and as it is for the local ones
+func (s StagingPath) String() string {
+	if s.IsLocal() {
+		return s.Dir
+	}
+	host := s.Host
+	if len(s.User) != 0 {
+		host = s.User + "@" + host
+	}
+	return host + ":" + s.Dir
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package transfer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cuttle-ai/db-toolkit/datastores/transfer"
+)
+
+func TestParseStagingPath(t *testing.T) {
+	cases := map[string]transfer.StagingPath{
+		"/home/user/data":                      {Scheme: transfer.SchemeFile, Dir: "/home/user/data"},
+		"data/dump":                            {Scheme: transfer.SchemeFile, Dir: "data/dump"},
+		"file:///home/user/data":               {Scheme: transfer.SchemeFile, Dir: "/home/user/data"},
+		"user@myserver.com:/home/user/data":    {Scheme: transfer.SchemeSFTP, User: "user", Host: "myserver.com", Dir: "/home/user/data"},
+		"myserver.com:data":                    {Scheme: transfer.SchemeSFTP, Host: "myserver.com", Dir: "data"},
+		"sftp://user@myserver.com:2222/h/data": {Scheme: transfer.SchemeSFTP, User: "user", Host: "myserver.com", Port: "2222", Dir: "/h/data"},
+	}
+	for location, expected := range cases {
+		s, err := transfer.ParseStagingPath(location)
+		if err != nil || s != expected {
+			t.Error("expected the staging path", location, "to be parsed as", expected, "got", s, err)
+		}
+	}
+	for _, location := range []string{"", "user@:/home", "ftp://myserver.com/data", "sftp:///data"} {
+		if _, err := transfer.ParseStagingPath(location); err == nil {
+			t.Error("expected an error while parsing the staging path", location)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := transfer.NewMemory()
+	if err := m.Put(context.Background(), strings.NewReader("a,b\n"), "/data/t.csv"); err != nil {
+		t.Error("error while putting the file", err)
+		return
+	}
+	if b, ok := m.File("/data/t.csv"); !ok || string(b) != "a,b\n" {
+		t.Error("expected the file /data/t.csv to have a,b. got", string(b), ok)
+	}
+	if err := m.Remove("/data/t.csv"); err != nil || len(m.Files()) != 0 {
+		t.Error("expected the file to be removed. got", m.Files(), err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Put(ctx, strings.NewReader("a,b\n"), "/data/t.csv"); err != context.Canceled {
+		t.Error("expected the put to be cancelled. got", err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"/data/sales.csv":            `'/data/sales.csv'`,
+		"/data/o'neil.csv":           `'/data/o'\''neil.csv'`,
+		"/data/x'; rm -rf ~; echo '": `'/data/x'\''; rm -rf ~; echo '\'''`,
+	}
+	for value, expected := range cases {
+		if quoted := transfer.ShellQuote(value); quoted != expected {
+			t.Error("expected", value, "to be quoted as", expected, "got", quoted)
+			return
+		}
+	}
+}
+
+func TestNewSFTPKnownHosts(t *testing.T) {
+	staging, _ := transfer.ParseStagingPath("user@myserver.com:/home/user/data")
+	if _, err := transfer.NewSFTP(staging, transfer.SFTPConfig{Password: "secret"}); err == nil {
+		t.Error("expected an error for sftp without the known hosts file")
+		return
+	}
+	if _, err := transfer.NewSFTP(staging, transfer.SFTPConfig{Password: "secret", KnownHostsFile: "~/.ssh/known_hosts"}); err != nil {
+		t.Error("error while creating the sftp transport with the known hosts file", err)
+	}
+}
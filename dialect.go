@@ -0,0 +1,41 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+const (
+	//DateUnitDay truncates a date to the start of its day
+	DateUnitDay = "day"
+	//DateUnitWeek truncates a date to the start of its week
+	DateUnitWeek = "week"
+	//DateUnitMonth truncates a date to the start of its month
+	DateUnitMonth = "month"
+	//DateUnitQuarter truncates a date to the start of its quarter
+	DateUnitQuarter = "quarter"
+	//DateUnitYear truncates a date to the start of its year
+	DateUnitYear = "year"
+)
+
+//Dialect renders the parts of the sql queries that differ between the datastores.
+//Each datastore exposes its dialect so that the queries can be generated without depending on the datastore
+type Dialect interface {
+	//QuoteIdentifier quotes the name of a table or a column so that it can be safely used in a query
+	QuoteIdentifier(name string) string
+	//Placeholder returns the placeholder for the nth argument of a query starting from 1
+	Placeholder(n int) string
+	//DataType returns the name of the type in the datastore for the interpreter data type like interpreter.DataTypeInt
+	DataType(dataType string) string
+	//ParseDate returns the expression parsing the text expression as a date with the date format.
+	//The date format is a layout of the time package like the date format of the columns
+	ParseDate(expr string, dateFormat string) string
+	//TruncateDate returns the expression truncating the date expression to the start of the date unit like DateUnitMonth
+	TruncateDate(expr string, unit string) string
+	//TruncateTable returns the statement removing all the rows in the table
+	TruncateTable(tablename string) string
+	//CountDistinct returns the expression counting the distinct values of the given expression
+	CountDistinct(expr string) string
+	//LimitOffset returns the clause limiting the rows of a query to limit rows after skipping offset rows.
+	//Zero limit or offset is left out of the clause
+	LimitOffset(limit int, offset int) string
+}
@@ -0,0 +1,33 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/postgres"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestPostgresDialect(t *testing.T) {
+	var d toolkit.Dialect = postgres.Dialect{}
+	cases := []struct {
+		got      string
+		expected string
+	}{
+		{d.DataType(interpreter.DataTypeDate), "date"},
+		{d.ParseDate(`"sold on"`, "2006-1-2"), `to_date("sold on", 'YYYY-mm-dd')`},
+		{d.TruncateDate(`"sold on"`, toolkit.DateUnitMonth), `date_trunc('month', "sold on")::date`},
+		{d.TruncateTable("sales"), `TRUNCATE TABLE "sales"`},
+		{d.LimitOffset(0, 20), " OFFSET 20"},
+	}
+	for _, c := range cases {
+		if c.got != c.expected {
+			t.Error("expected", c.expected, "got", c.got)
+			return
+		}
+	}
+}
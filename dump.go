@@ -0,0 +1,202 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//ValidationMode decides how the rows not matching the data types of the columns are handled while dumping data to a datastore
+type ValidationMode int
+
+const (
+	//ValidationNone won't validate the rows. The data is handed over to the datastore as it is
+	ValidationNone ValidationMode = iota
+	//ValidationAbort will abort the dump at the first invalid row
+	ValidationAbort
+	//ValidationSkip will skip the invalid rows and load the rest of them
+	ValidationSkip
+	//ValidationReject will load the invalid rows to a side table instead of the table
+	ValidationReject
+)
+
+//DefaultMaxRowErrors is the maximum no. of row errors kept in a dump result if not specified in the dump options
+const DefaultMaxRowErrors = 1000
+
+//DumpOptions has the options for dumping data to a datastore
+type DumpOptions struct {
+	//AppendData if set will append the data to the existing data in the table instead of replacing it
+	AppendData bool
+	//EvolveSchema if set along with AppendData will evolve the schema of the existing table to match the incoming data.
+	//New columns are added to the table, the columns missing in the data are filled with nulls
+	//and the data types are widened when required. The changes are reported in the dump result
+	EvolveSchema bool
+	//CreateTable if set will create the table before dumping the data
+	CreateTable bool
+	//DoScp is not used anymore. The files are transferred using the transport of the datastore
+	//configured on the datastore service or chosen from its data dump directory
+	DoScp bool
+	//Dialect is the format in which the csv file is written
+	Dialect CSVDialect
+	//Validation is the mode in which the rows are validated against the data types of the columns
+	Validation ValidationMode
+	//RejectTable is the side table to which the invalid rows are loaded in ValidationReject mode.
+	//Defaults to the table name suffixed with _rejected_rows
+	RejectTable string
+	//MaxRowErrors is the maximum no. of row errors kept in the dump result. Defaults to DefaultMaxRowErrors
+	MaxRowErrors int
+	//ShadowReplace if set will replace the data in the table without blocking its readers.
+	//The data is loaded to a shadow table which is swapped with the table once loaded.
+	//The replaced version of the table is kept until the next replace for restoring it if required.
+	//It is used only when the data is neither appended nor merged to an existing table.
+	//Tables having views depending on them can't be replaced using a shadow table as the views would follow the replaced version
+	ShadowReplace bool
+	//ExpectedRows if set along with ShadowReplace is the no. of rows expected in the shadow table.
+	//The shadow table is swapped with the table only if it has these many rows
+	ExpectedRows int64
+	//MergeKeys if set will merge the data with the existing data in the table using the key columns instead of replacing it.
+	//Rows having new keys are inserted and the rows matching the existing keys are updated
+	MergeKeys []string
+	//DeleteMissing if set along with MergeKeys will delete the rows in the table whose keys are missing in the data
+	DeleteMissing bool
+	//ChunkRows is the no. of rows committed in each chunk while ingesting the data resumably. Defaults to DefaultChunkRows
+	ChunkRows int
+	//Progress if set is called with the progress of the dump
+	Progress ProgressFunc
+	//Stream if set streams the csv file to the datastore instead of transferring it to the data dump directory and copying it from there.
+	//The bytes read and the rows loaded are reported to the progress func while the rows are copied
+	Stream bool
+	//Context if set can cancel the dump. The dump is aborted and the changes are rolled back once the context is done
+	Context context.Context
+	//OverflowColumn if set is the json column in which the structure not mapped to the columns is stored.
+	//It is used only by the sources having nested structures like json lines
+	OverflowColumn string
+}
+
+//RowError has the info about a row that failed the validation
+type RowError struct {
+	//Line is the line number in the file at which the row starts
+	Line int64
+	//Column is the name of the column that failed the validation. It will be empty if the whole row is invalid
+	Column string
+	//Value is the value that failed the validation
+	Value string
+	//Reason says why the validation failed
+	Reason string
+}
+
+//DumpResult has the report of dumping data to a datastore
+type DumpResult struct {
+	//RowsLoaded is the no. of rows loaded to the table
+	RowsLoaded int64
+	//RowsRejected is the no. of rows that failed the validation
+	RowsRejected int64
+	//RowsInserted is the no. of new rows inserted while merging the data using the merge keys
+	RowsInserted int64
+	//RowsUpdated is the no. of existing rows updated while merging the data using the merge keys
+	RowsUpdated int64
+	//RowsDeleted is the no. of existing rows deleted while merging the data as their keys were missing in the data
+	RowsDeleted int64
+	//RowErrors has the errors of the rows that failed the validation. It is capped at the MaxRowErrors of the dump options
+	RowErrors []RowError
+	//SchemaChanges has the changes made to the schema of the table while appending the data when the schema is evolved
+	SchemaChanges []SchemaChange
+}
+
+//AddRowError adds a row error to the result. The row errors are capped at max or DefaultMaxRowErrors if max is not set
+func (r *DumpResult) AddRowError(rErr RowError, max int) {
+	r.RowsRejected++
+	if max <= 0 {
+		max = DefaultMaxRowErrors
+	}
+	if len(r.RowErrors) < max {
+		r.RowErrors = append(r.RowErrors, rErr)
+	}
+}
+
+//ValidateMergeKeys validates whether the merge keys are present in the columns and are not repeated
+func ValidateMergeKeys(keys []string, columns []interpreter.ColumnNode) error {
+	colMap := map[string]bool{}
+	for _, col := range columns {
+		colMap[col.Name] = true
+	}
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if !colMap[k] {
+			return errors.New("couldn't find the merge key " + k + " in the columns")
+		}
+		if seen[k] {
+			return errors.New("merge key " + k + " is repeated")
+		}
+		seen[k] = true
+	}
+	return nil
+}
+
+//MergeIndexName returns the name of the unique index created on the merge keys of a table.
+//The name has the merge keys so that merging on different keys creates a different index.
+//Names longer than the maximum identifier length are truncated with a hash of the table and the keys
+func MergeIndexName(tablename string, keys []string) string {
+	name := tablename + "_" + strings.Join(keys, "_") + "_merge_keys"
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+	sum := sha1.Sum([]byte(tablename + "." + strings.Join(keys, ".")))
+	hash := hex.EncodeToString(sum[:8])
+	return name[:maxIdentifierLength-len(hash)-1] + "_" + hash
+}
+
+//ShadowTableName returns the name of the shadow table to which the data is loaded while replacing the table without blocking its readers
+func ShadowTableName(tablename string) string {
+	return tablename + "_shadow"
+}
+
+//PreviousTableName returns the name of the table having the previous version of the table replaced using a shadow table
+func PreviousTableName(tablename string) string {
+	return tablename + "_previous"
+}
+
+//ChildTableName returns the name of the table for a part of a source like a sheet in a workbook.
+//The name of the part is lower cased with the characters other than letters and digits replaced by underscore
+func ChildTableName(tablename string, part string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, part)
+	return tablename + "_" + name
+}
+
+//RowReader reads the rows from a data source one at a time
+type RowReader interface {
+	//Read returns the next row in the source. Nil values in the row are nulls. It returns io.EOF when there are no more rows
+	Read() ([]interface{}, error)
+	//Line returns the position in the source like the line number at which the last read row started
+	Line() int
+}
+
+//RawRowReader is a row reader that also has the text of the last read row as it was in the source.
+//The readers of the text sources like the csv and the json lines are raw row readers
+type RawRowReader interface {
+	RowReader
+	//Raw returns the text of the last read row as it was in the source. It is available for the malformed rows too
+	Raw() string
+}
+
+//RawRow returns the text of the last row read from the reader if it is a raw row reader else an empty string
+func RawRow(r RowReader) string {
+	if raw, ok := r.(RawRowReader); ok {
+		return raw.Raw()
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import "errors"
+
+var (
+	//ErrInvalidValue is the error when a value can't be stored in or converted to the data type of a column like a bad date value
+	ErrInvalidValue = errors.New("invalid value")
+	//ErrConstraintViolation is the error when a change violates a constraint on a table like a duplicate key or a null in a not null column
+	ErrConstraintViolation = errors.New("constraint violation")
+	//ErrPermissionDenied is the error when the user of the datastore isn't allowed to do an operation or can't be authenticated
+	ErrPermissionDenied = errors.New("permission denied")
+	//ErrSyntax is the error when a query has a syntax error or uses an unknown function or type
+	ErrSyntax = errors.New("syntax error")
+	//ErrConnection is the error when the connection to the datastore couldn't be made or was lost
+	ErrConnection = errors.New("connection lost")
+	//ErrTooManyConnections is the error when the datastore doesn't accept more connections
+	ErrTooManyConnections = errors.New("too many connections")
+	//ErrInsufficientResources is the error when the datastore ran out of disk, memory or other resources
+	ErrInsufficientResources = errors.New("insufficient resources")
+	//ErrSerialization is the error when a transaction couldn't be serialized with the concurrent transactions
+	ErrSerialization = errors.New("serialization failure")
+	//ErrDeadlock is the error when a transaction was aborted to break a deadlock
+	ErrDeadlock = errors.New("deadlock detected")
+	//ErrCanceled is the error when an operation was canceled or timed out
+	ErrCanceled = errors.New("operation canceled")
+)
+
+//DatastoreError is an error of a datastore translated from the error of its driver.
+//The kind of the error can be checked using errors.Is like errors.Is(err, ErrPermissionDenied)
+//and the error of the driver can be got using errors.As
+type DatastoreError struct {
+	//Kind is the kind of the error like ErrPermissionDenied
+	Kind error
+	//Code is the code of the error in the datastore like the SQLSTATE code of the error. It is empty if not known
+	Code string
+	//Message is the message of the error. It is used only when there is no error from the driver
+	Message string
+	//Err is the error of the driver
+	Err error
+}
+
+//Error returns the error message of the driver or the message if there is no error from the driver
+func (d *DatastoreError) Error() string {
+	if d.Err != nil {
+		return d.Err.Error()
+	}
+	if len(d.Message) != 0 {
+		return d.Message
+	}
+	return d.Kind.Error()
+}
+
+//Is returns true if the target is the kind of the error
+func (d *DatastoreError) Is(target error) bool {
+	return target == d.Kind
+}
+
+//Unwrap returns the error of the driver
+func (d *DatastoreError) Unwrap() error {
+	return d.Err
+}
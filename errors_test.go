@@ -0,0 +1,50 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"errors"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+//driverError is an error from a database driver
+type driverError struct {
+	code string
+}
+
+func (d *driverError) Error() string {
+	return "pq: permission denied for table sales"
+}
+
+func TestDatastoreError(t *testing.T) {
+	var err error = &toolkit.TableError{
+		Op:    "truncate",
+		Table: "sales",
+		Err:   &toolkit.DatastoreError{Kind: toolkit.ErrPermissionDenied, Code: "42501", Err: &driverError{code: "42501"}},
+	}
+	if !errors.Is(err, toolkit.ErrPermissionDenied) {
+		t.Error("expected the error to be of the kind", toolkit.ErrPermissionDenied, "got", err)
+		return
+	}
+	if errors.Is(err, toolkit.ErrTableNotFound) {
+		t.Error("expected the error not to be of the kind", toolkit.ErrTableNotFound)
+		return
+	}
+	var dErr *toolkit.DatastoreError
+	if !errors.As(err, &dErr) || dErr.Code != "42501" {
+		t.Error("expected the datastore error with the code 42501. got", err)
+		return
+	}
+	var drvErr *driverError
+	if !errors.As(err, &drvErr) {
+		t.Error("expected the error of the driver to be available. got", err)
+		return
+	}
+	if err.Error() != "truncate sales: pq: permission denied for table sales" {
+		t.Error("expected the error message of the driver. got", err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"errors"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//SchemaChangeKind is the kind of change made to the schema of a table while appending data to it
+type SchemaChangeKind int
+
+const (
+	//SchemaChangeAddColumn is a column present in the incoming data that is added to the table
+	SchemaChangeAddColumn SchemaChangeKind = iota
+	//SchemaChangeWidenColumn is a column whose data type is widened to store the incoming data like int to float
+	SchemaChangeWidenColumn
+	//SchemaChangeMissingColumn is a column in the table missing in the incoming data. It is filled with nulls
+	SchemaChangeMissingColumn
+)
+
+//SchemaChange is a change made to the schema of a table while appending data to it
+type SchemaChange struct {
+	//Kind is the kind of the change
+	Kind SchemaChangeKind
+	//Column is the name of the column changed
+	Column string
+	//From is the data type of the column before the change. It is empty for the added columns
+	From string
+	//To is the data type of the column after the change. It is empty for the missing columns
+	To string
+}
+
+//String returns the description of the schema change
+func (s SchemaChange) String() string {
+	switch s.Kind {
+	case SchemaChangeAddColumn:
+		return "added the column " + s.Column + " as " + s.To
+	case SchemaChangeWidenColumn:
+		return "widened the column " + s.Column + " from " + s.From + " to " + s.To
+	default:
+		return "filled the column " + s.Column + " missing in the data with nulls"
+	}
+}
+
+//WidenDataType returns the data type that can store the values of both the existing and the incoming data types.
+//Ints are widened to float to store floats. Rest of the mismatching data types like dates and texts are widened to string
+func WidenDataType(existing string, incoming string) string {
+	switch {
+	case existing == incoming:
+		return existing
+	case existing == interpreter.DataTypeString:
+		return existing
+	case existing == interpreter.DataTypeFloat && incoming == interpreter.DataTypeInt:
+		return existing
+	case existing == interpreter.DataTypeInt && incoming == interpreter.DataTypeFloat:
+		return interpreter.DataTypeFloat
+	default:
+		return interpreter.DataTypeString
+	}
+}
+
+//PlanSchemaEvolution compares the columns of the incoming data with the existing columns of a table.
+//It returns the changes required in the table for appending the incoming data to it.
+//Columns are matched by their name. The columns added are listed first followed by the widened and the missing ones
+func PlanSchemaEvolution(existing []Column, incoming []interpreter.ColumnNode) ([]SchemaChange, error) {
+	if len(existing) == 0 {
+		return nil, errors.New("couldn't find the columns of the table to evolve its schema")
+	}
+	existingMap := map[string]Column{}
+	for _, col := range existing {
+		existingMap[col.Name] = col
+	}
+	incomingMap := map[string]bool{}
+	added := []SchemaChange{}
+	widened := []SchemaChange{}
+	for _, col := range incoming {
+		incomingMap[col.Name] = true
+		ex, ok := existingMap[col.Name]
+		if !ok {
+			added = append(added, SchemaChange{Kind: SchemaChangeAddColumn, Column: col.Name, To: col.DataType})
+			continue
+		}
+		if to := WidenDataType(ex.DataType, col.DataType); to != ex.DataType {
+			widened = append(widened, SchemaChange{Kind: SchemaChangeWidenColumn, Column: col.Name, From: ex.DataType, To: to})
+		}
+	}
+	changes := append(added, widened...)
+	for _, col := range existing {
+		if !incomingMap[col.Name] {
+			changes = append(changes, SchemaChange{Kind: SchemaChangeMissingColumn, Column: col.Name, From: col.DataType})
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"reflect"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestPlanSchemaEvolution(t *testing.T) {
+	existing := []toolkit.Column{
+		{Name: "id", DataType: interpreter.DataTypeInt},
+		{Name: "price", DataType: interpreter.DataTypeInt},
+		{Name: "sold on", DataType: interpreter.DataTypeDate},
+		{Name: "region", DataType: interpreter.DataTypeString},
+	}
+	incoming := []interpreter.ColumnNode{
+		{Name: "id", DataType: interpreter.DataTypeInt},
+		{Name: "price", DataType: interpreter.DataTypeFloat},
+		{Name: "sold on", DataType: interpreter.DataTypeString},
+		{Name: "discount", DataType: interpreter.DataTypeFloat},
+	}
+	changes, err := toolkit.PlanSchemaEvolution(existing, incoming)
+	if err != nil {
+		t.Error("error while planning the schema evolution", err)
+		return
+	}
+	expected := []toolkit.SchemaChange{
+		{Kind: toolkit.SchemaChangeAddColumn, Column: "discount", To: interpreter.DataTypeFloat},
+		{Kind: toolkit.SchemaChangeWidenColumn, Column: "price", From: interpreter.DataTypeInt, To: interpreter.DataTypeFloat},
+		{Kind: toolkit.SchemaChangeWidenColumn, Column: "sold on", From: interpreter.DataTypeDate, To: interpreter.DataTypeString},
+		{Kind: toolkit.SchemaChangeMissingColumn, Column: "region", From: interpreter.DataTypeString},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Error("expected the schema changes", expected, "got", changes)
+	}
+}
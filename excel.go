@@ -0,0 +1,642 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//ExcelOptions has the options for reading the sheets in an excel workbook
+type ExcelOptions struct {
+	//Sheet is the name of the sheet to be read. Defaults to the first sheet in the workbook
+	Sheet string
+	//AllSheets if set will read all the sheets in the workbook. Each sheet is dumped to its own table
+	AllSheets bool
+	//SkipRows is the no. of leading rows in the sheet to be skipped before the header
+	SkipRows int
+	//HeaderRows is the no. of rows having the header. Merged cells in the header rows are spread across the range
+	//and the header rows are joined with a space to get the column names. Defaults to 1
+	HeaderRows int
+}
+
+//ExcelReader reads the rows from a sheet in an excel workbook (.xlsx).
+//Numeric cells are read as numbers, cells formatted as dates as time, booleans as true or false and formulas as their cached values
+type ExcelReader struct {
+	z        *zip.ReadCloser
+	sheet    io.ReadCloser
+	d        *xml.Decoder
+	strs     []string
+	dates    map[int]bool
+	date1904 bool
+	columns  []interpreter.ColumnNode
+	selected []int
+	line     int
+}
+
+//excelSheet has the name of a sheet and the path of its xml in the workbook
+type excelSheet struct {
+	name string
+	path string
+}
+
+//ExcelSheets returns the names of the sheets in the given excel workbook
+func ExcelSheets(filename string) ([]string, error) {
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	sheets, _, err := readExcelWorkbook(&z.Reader)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(sheets))
+	for i, s := range sheets {
+		names[i] = s.name
+	}
+	return names, nil
+}
+
+//NewExcelReader returns a reader reading the rows from a sheet in the given excel workbook as per the options
+func NewExcelReader(filename string, opts ExcelOptions) (*ExcelReader, error) {
+	/*
+	 * We will open the workbook and find the sheet
+	 * Then we will read the shared strings and the styles
+	 * Then we will read the merged cells in the sheet
+	 * Then we will skip the leading rows and read the header
+	 */
+	//opening the workbook
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := &ExcelReader{z: z}
+	sheets, date1904, err := readExcelWorkbook(&z.Reader)
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+	if len(sheets) == 0 {
+		z.Close()
+		return nil, errors.New("couldn't find any sheet in the workbook " + filename)
+	}
+	r.date1904 = date1904
+	sheet := sheets[0]
+	if len(opts.Sheet) != 0 {
+		sheet.path = ""
+		for _, s := range sheets {
+			if s.name == opts.Sheet {
+				sheet = s
+			}
+		}
+		if len(sheet.path) == 0 {
+			z.Close()
+			return nil, errors.New("couldn't find the sheet " + opts.Sheet + " in the workbook " + filename)
+		}
+	}
+
+	//reading the shared strings and the styles
+	if r.strs, err = readExcelSharedStrings(&z.Reader); err != nil {
+		z.Close()
+		return nil, err
+	}
+	if r.dates, err = readExcelDateStyles(&z.Reader); err != nil {
+		z.Close()
+		return nil, err
+	}
+
+	//reading the merged cells
+	merges, err := readExcelMerges(&z.Reader, sheet.path)
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+
+	//skipping the leading rows and reading the header
+	f := findZipFile(&z.Reader, sheet.path)
+	if r.sheet, err = f.Open(); err != nil {
+		z.Close()
+		return nil, err
+	}
+	r.d = xml.NewDecoder(r.sheet)
+	headerRows := opts.HeaderRows
+	if headerRows <= 0 {
+		headerRows = 1
+	}
+	header := [][]string{}
+	for len(header) < headerRows {
+		num, cells, err := r.readRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		if num <= opts.SkipRows {
+			continue
+		}
+		if num > opts.SkipRows+headerRows {
+			r.Close()
+			return nil, errors.New("expected the header at row " + strconv.Itoa(opts.SkipRows+1) + " of the sheet " + sheet.name)
+		}
+		header = append(header, excelHeaderCells(num, cells, merges))
+	}
+	r.columns = excelColumns(header)
+	r.Select(r.columns)
+	return r, nil
+}
+
+//Columns returns the columns in the header of the sheet. The data type of the columns is string
+func (r *ExcelReader) Columns() []interpreter.ColumnNode {
+	return r.columns
+}
+
+//Select sets the columns to be read by the reader matching them with the header by name.
+//The rows are read in the order of the given columns. Columns not present in the header are read as nulls
+func (r *ExcelReader) Select(columns []interpreter.ColumnNode) {
+	colIndex := map[string]int{}
+	for i, col := range r.columns {
+		colIndex[col.Name] = i
+	}
+	r.selected = make([]int, len(columns))
+	for i, col := range columns {
+		r.selected[i] = -1
+		if j, ok := colIndex[col.Name]; ok {
+			r.selected[i] = j
+		}
+	}
+}
+
+//Line returns the row number in the sheet of the last read row
+func (r *ExcelReader) Line() int {
+	return r.line
+}
+
+//Read reads the next row from the sheet. Rows without any values are skipped. It returns io.EOF when there are no more rows
+func (r *ExcelReader) Read() ([]interface{}, error) {
+	_, cells, err := r.readRow()
+	for err == nil && len(cells) == 0 {
+		_, cells, err = r.readRow()
+	}
+	if err != nil {
+		return nil, err
+	}
+	row := make([]interface{}, len(r.selected))
+	for i, j := range r.selected {
+		if j >= 0 {
+			row[i] = cells[j]
+		}
+	}
+	return row, nil
+}
+
+//Close closes the workbook
+func (r *ExcelReader) Close() error {
+	if r.sheet != nil {
+		r.sheet.Close()
+	}
+	return r.z.Close()
+}
+
+//readRow reads the next row in the sheet. It returns the row number and the cells in the row by the column index
+func (r *ExcelReader) readRow() (int, map[int]interface{}, error) {
+	/*
+	 * We will find the start of the next row
+	 * Then we will read the cells in the row till the end of the row
+	 */
+	//finding the start of the row
+	num := 0
+	for {
+		tok, err := r.d.Token()
+		if err != nil {
+			return 0, nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "row" {
+			num, _ = strconv.Atoi(xmlAttr(se, "r"))
+			break
+		}
+	}
+	if num == 0 {
+		num = r.line + 1
+	}
+	r.line = num
+
+	//reading the cells
+	cells := map[int]interface{}{}
+	next := 0
+	for {
+		tok, err := r.d.Token()
+		if err != nil {
+			return 0, nil, err
+		}
+		if ee, ok := tok.(xml.EndElement); ok && ee.Name.Local == "row" {
+			return num, cells, nil
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "c" {
+			continue
+		}
+		var c excelCell
+		if err := r.d.DecodeElement(&c, &se); err != nil {
+			return 0, nil, err
+		}
+		col := next
+		if len(c.Ref) != 0 {
+			col, _ = excelCellIndex(c.Ref)
+		}
+		next = col + 1
+		if v := r.cellValue(c); v != nil {
+			cells[col] = v
+		}
+	}
+}
+
+//excelCell is a cell in the sheet xml
+type excelCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Style  int    `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+//cellValue returns the typed value of the cell
+func (r *ExcelReader) cellValue(c excelCell) interface{} {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(r.strs) {
+			return nil
+		}
+		return r.strs[i]
+	case "inlineStr":
+		text := c.Inline.Text
+		for _, run := range c.Inline.Runs {
+			text += run.Text
+		}
+		return text
+	case "str":
+		//cached string value of a formula
+		return c.Value
+	case "b":
+		return strconv.FormatBool(c.Value == "1")
+	case "e":
+		//error values like #N/A are considered as null
+		return nil
+	}
+	if len(c.Value) == 0 {
+		return nil
+	}
+	f, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return c.Value
+	}
+	if r.dates[c.Style] {
+		return excelTime(f, r.date1904)
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+	return f
+}
+
+//headerCells returns the cells in a header row as strings with the merged cells spread across their range
+func excelHeaderCells(num int, cells map[int]interface{}, merges []excelRange) []string {
+	width := 0
+	for col := range cells {
+		if col+1 > width {
+			width = col + 1
+		}
+	}
+	for _, m := range merges {
+		if num >= m.fromRow && num <= m.toRow && m.toCol+1 > width {
+			width = m.toCol + 1
+		}
+	}
+	header := make([]string, width)
+	for col, v := range cells {
+		header[col], _ = FormatValue(v)
+	}
+	for _, m := range merges {
+		if num < m.fromRow || num > m.toRow {
+			continue
+		}
+		//the value of a merged range is in its top left cell. The header rows after the first row of the range
+		//will have it empty as the value was already read into the header of the first row
+		v := header[m.fromCol]
+		for col := m.fromCol; col <= m.toCol; col++ {
+			header[col] = v
+		}
+	}
+	return header
+}
+
+//excelColumns joins the header rows to get the columns. Consecutive duplicate values from the merged cells are joined once
+func excelColumns(header [][]string) []interpreter.ColumnNode {
+	width := 0
+	for _, h := range header {
+		if len(h) > width {
+			width = len(h)
+		}
+	}
+	columns := make([]interpreter.ColumnNode, width)
+	for col := 0; col < width; col++ {
+		parts := []string{}
+		for _, h := range header {
+			if col >= len(h) {
+				continue
+			}
+			v := strings.TrimSpace(h[col])
+			if len(v) == 0 || (len(parts) > 0 && parts[len(parts)-1] == v) {
+				continue
+			}
+			parts = append(parts, v)
+		}
+		name := strings.Join(parts, " ")
+		if len(name) == 0 {
+			name = "column_" + strconv.Itoa(col+1)
+		}
+		columns[col] = interpreter.ColumnNode{Name: name, DataType: interpreter.DataTypeString}
+	}
+	return columns
+}
+
+//excelRange is a range of merged cells with 1 based rows and 0 based columns
+type excelRange struct {
+	fromRow, toRow int
+	fromCol, toCol int
+}
+
+//readExcelMerges reads the merged cells in the sheet. They are listed after the data in the sheet xml
+func readExcelMerges(z *zip.Reader, sheetPath string) ([]excelRange, error) {
+	f := findZipFile(z, sheetPath)
+	if f == nil {
+		return nil, errors.New("couldn't find the sheet " + sheetPath + " in the workbook")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	d := xml.NewDecoder(rc)
+	merges := []excelRange{}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return merges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local == "sheetData" {
+			d.Skip()
+			continue
+		}
+		if se.Name.Local != "mergeCell" {
+			continue
+		}
+		refs := strings.Split(xmlAttr(se, "ref"), ":")
+		if len(refs) != 2 {
+			continue
+		}
+		fromCol, fromRow := excelCellIndex(refs[0])
+		toCol, toRow := excelCellIndex(refs[1])
+		merges = append(merges, excelRange{fromRow: fromRow, toRow: toRow, fromCol: fromCol, toCol: toCol})
+	}
+}
+
+//readExcelWorkbook reads the sheets in the workbook and whether the dates in the workbook are based on 1904
+func readExcelWorkbook(z *zip.Reader) ([]excelSheet, bool, error) {
+	/*
+	 * We will read the relationships of the workbook to get the path of the sheets
+	 * Then we will read the sheets in the workbook
+	 */
+	//reading the relationships
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(z, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, false, err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+
+	//reading the sheets
+	var wb struct {
+		Properties struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(z, "xl/workbook.xml", &wb); err != nil {
+		return nil, false, err
+	}
+	sheets := []excelSheet{}
+	for _, s := range wb.Sheets {
+		for _, a := range s.Attr {
+			if a.Name.Local == "id" {
+				sheets = append(sheets, excelSheet{name: s.Name, path: targets[a.Value]})
+			}
+		}
+	}
+	date1904 := wb.Properties.Date1904 == "1" || wb.Properties.Date1904 == "true"
+	return sheets, date1904, nil
+}
+
+//readExcelSharedStrings reads the shared strings in the workbook. Phonetic runs in the strings are ignored
+func readExcelSharedStrings(z *zip.Reader) ([]string, error) {
+	f := findZipFile(z, "xl/sharedStrings.xml")
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	d := xml.NewDecoder(rc)
+	strs := []string{}
+	var text strings.Builder
+	inText := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				text.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				d.Skip()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, text.String())
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+//readExcelDateStyles reads the styles in the workbook and returns the indices of the cell styles having a date format
+func readExcelDateStyles(z *zip.Reader) (map[int]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	dates := map[int]bool{}
+	if findZipFile(z, "xl/styles.xml") == nil {
+		return dates, nil
+	}
+	if err := decodeZipXML(z, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+	custom := map[int]string{}
+	for _, n := range styles.NumFmts {
+		custom[n.ID] = n.Code
+	}
+	for i, xf := range styles.CellXfs {
+		if code, ok := custom[xf.NumFmtID]; ok {
+			dates[i] = isExcelDateFormat(code)
+			continue
+		}
+		id := xf.NumFmtID
+		dates[i] = (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+	}
+	return dates, nil
+}
+
+//isExcelDateFormat returns true if the number format code formats the numbers as date or time
+func isExcelDateFormat(code string) bool {
+	inQuotes := false
+	inBrackets := false
+	escaped := false
+	for _, ru := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case ru == '\\':
+			escaped = true
+		case ru == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case ru == '[':
+			inBrackets = true
+		case ru == ']':
+			inBrackets = false
+		case inBrackets:
+		case strings.ContainsRune("ymdhsYMDHS", ru):
+			return true
+		}
+	}
+	return false
+}
+
+//excelTime converts the serial no. of a date in excel to time.
+//Serials in 1900 based workbooks account for 29th February 1900 which excel considers as a valid date
+func excelTime(serial float64, date1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	} else if serial < 61 {
+		epoch = time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	secs := math.Round((serial - days) * 24 * 60 * 60)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second)
+}
+
+//excelCellIndex returns the 0 based column index and the 1 based row number of a cell reference like B3
+func excelCellIndex(ref string) (int, int) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && unicode.IsLetter(rune(ref[i])); i++ {
+		col = col*26 + int(unicode.ToUpper(rune(ref[i]))-'A'+1)
+	}
+	row, _ := strconv.Atoi(ref[i:])
+	return col - 1, row
+}
+
+func xmlAttr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func findZipFile(z *zip.Reader, name string) *zip.File {
+	for _, f := range z.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func decodeZipXML(z *zip.Reader, name string, v interface{}) error {
+	f := findZipFile(z, name)
+	if f == nil {
+		return errors.New("couldn't find " + name + " in the workbook")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//workbook has the minimal parts of an excel workbook having a sheet with a title row, a merged header and typed cells
+var workbook = map[string]string{
+	"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sales" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+	"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Monthly report</t></si><si><t>Item</t></si><si><t>Sold</t></si><si><r><t>Qua</t></r><r><t>ntity</t></r></si><si><t>On</t></si><si><t>apple</t></si></sst>`,
+	"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/></numFmts>
+<cellXfs count="2"><xf numFmtId="0"/><xf numFmtId="164"/></cellXfs></styleSheet>`,
+	"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c></row>
+<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2" t="s"><v>2</v></c></row>
+<row r="3"><c r="B3" t="s"><v>3</v></c><c r="C3" t="s"><v>4</v></c><c r="D3" t="inlineStr"><is><t>Fresh</t></is></c></row>
+<row r="4"><c r="A4" t="s"><v>5</v></c><c r="B4"><f>1+2</f><v>3</v></c><c r="C4" s="1"><v>43800</v></c><c r="D4" t="b"><v>1</v></c></row>
+<row r="5"><c r="A5" t="str"><f>"pe"&amp;"ar"</f><v>pear</v></c><c r="B5"><v>2.5</v></c><c r="C5" t="e"><v>#N/A</v></c></row>
+</sheetData><mergeCells count="2"><mergeCell ref="A2:A3"/><mergeCell ref="B2:C2"/></mergeCells></worksheet>`,
+}
+
+func TestExcelReader(t *testing.T) {
+	f, err := ioutil.TempFile("", "sales-*.xlsx")
+	if err != nil {
+		t.Error("error while creating the test workbook", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	z := zip.NewWriter(f)
+	for name, content := range workbook {
+		w, _ := z.Create(name)
+		w.Write([]byte(content))
+	}
+	z.Close()
+	f.Close()
+
+	r, err := toolkit.NewExcelReader(f.Name(), toolkit.ExcelOptions{SkipRows: 1, HeaderRows: 2})
+	if err != nil {
+		t.Error("error while opening the workbook", err)
+		return
+	}
+	defer r.Close()
+	names := []string{}
+	for _, col := range r.Columns() {
+		names = append(names, col.Name)
+	}
+	if expected := []string{"Item", "Sold Quantity", "Sold On", "Fresh"}; !reflect.DeepEqual(names, expected) {
+		t.Error("expected the columns", expected, "got", names)
+	}
+	r.Select([]interpreter.ColumnNode{{Name: "Item"}, {Name: "Sold Quantity"}, {Name: "Sold On"}, {Name: "Fresh"}})
+	expected := [][]interface{}{
+		{"apple", int64(3), time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC), "true"},
+		{"pear", 2.5, nil, nil},
+	}
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			if i != len(expected) {
+				t.Error("expected", len(expected), "rows. got", i)
+			}
+			return
+		}
+		if err != nil {
+			t.Error("error while reading the row", err)
+			return
+		}
+		if !reflect.DeepEqual(row, expected[i]) || r.Line() != i+4 {
+			t.Error("expected", expected[i], "at row", i+4, "got", row, "at row", r.Line())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"context"
+	"time"
+
+	"github.com/cuttle-ai/brain/log"
+)
+
+//ExportDateFormat is the format in which the values of the date columns are exported
+const ExportDateFormat = "2006-01-02"
+
+//ExportOptions has the options for exporting a table or the result of a query from a datastore as csv
+type ExportOptions struct {
+	//Dialect is the format in which the csv is written. The header is not written if the dialect has no header
+	Dialect CSVDialect
+	//Header if set is written as the header instead of the names of the columns
+	Header []string
+	//Context if set can cancel the export
+	Context context.Context
+	//Logger if set is used to log the errors while exporting
+	Logger log.Log
+}
+
+//ExportValue formats a value read from a datastore for exporting it as csv. It returns nil for the nulls.
+//If date is set, the time values are formatted in ExportDateFormat. Rest of the values are formatted using FormatValue
+func ExportValue(v interface{}, date bool) interface{} {
+	if t, ok := v.(time.Time); ok && date {
+		return t.Format(ExportDateFormat)
+	}
+	str, null := FormatValue(v)
+	if null {
+		return nil
+	}
+	return str
+}
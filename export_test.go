@@ -0,0 +1,43 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestExportValue(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	row := []interface{}{
+		toolkit.ExportValue(day, true),
+		toolkit.ExportValue(day, false),
+		toolkit.ExportValue([]byte("north, east"), false),
+		toolkit.ExportValue(nil, true),
+		toolkit.ExportValue(int64(42), false),
+	}
+	buf := &bytes.Buffer{}
+	w, err := toolkit.NewCSVWriter(buf, toolkit.CSVDialect{})
+	if err != nil {
+		t.Error("error while creating the csv writer", err)
+		return
+	}
+	if err := w.Write(row); err != nil {
+		t.Error("error while writing the row", err)
+		return
+	}
+	if err := w.Flush(); err != nil {
+		t.Error("error while flushing the csv", err)
+		return
+	}
+	expected := "2020-01-02,2020-01-02T00:00:00Z,\"north, east\",,42\n"
+	if buf.String() != expected {
+		t.Error("expected the exported row", expected, "got", buf.String())
+		return
+	}
+}
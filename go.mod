@@ -9,6 +9,11 @@ replace github.com/cuttle-ai/brain => ../brain/
 require (
 	github.com/cuttle-ai/brain v0.0.0-00010101000000-000000000000
 	github.com/cuttle-ai/octopus v0.0.0-00010101000000-000000000000
+	github.com/jackc/pgconn v1.3.2
 	github.com/jinzhu/gorm v1.9.12
+	github.com/klauspost/compress v1.9.7
 	github.com/lib/pq v1.3.0
+	github.com/pkg/sftp v1.11.0
+	github.com/xitongsys/parquet-go v1.5.2
+	golang.org/x/crypto v0.0.0-20191205180655-e7c4368fe9dd
 )
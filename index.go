@@ -0,0 +1,104 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+const (
+	//IndexMethodBTree is the btree index suitable for filtering and grouping the values of a column
+	IndexMethodBTree = "btree"
+	//IndexMethodBRIN is the block range index suitable for the ranges of a column whose values follow the order in which the rows are stored.
+	//It is much smaller than a btree index for large tables
+	IndexMethodBRIN = "brin"
+)
+
+//BRINMinRows is the estimated no. of rows from which the date column of an append only table is indexed using IndexMethodBRIN
+const BRINMinRows int64 = 1000000
+
+//AutoIndexComment marks the indexes created automatically so that they can be told apart from the indexes created by the users
+const AutoIndexComment = "db-toolkit auto index"
+
+//maxIdentifierLength is the maximum length of the names of the objects in the datastores
+const maxIdentifierLength = 63
+
+//AutoIndex is an index created automatically on a column of a table
+type AutoIndex struct {
+	//Column is the name of the column indexed
+	Column string
+	//Method is the access method of the index like IndexMethodBTree or IndexMethodBRIN
+	Method string
+}
+
+//AutoIndexName returns the name of the index created automatically on the column of the table.
+//Names longer than the limit of the datastores are shortened with a hash of the table and the column
+func AutoIndexName(tablename string, colName string) string {
+	name := tablename + "_" + colName + "_auto_idx"
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+	sum := sha1.Sum([]byte(tablename + "." + colName))
+	hash := hex.EncodeToString(sum[:8])
+	return name[:maxIdentifierLength-len(hash)-1] + "_" + hash
+}
+
+//AutoIndexMethod returns the access method of the index for a column with the given data type in a table having the given no. of rows.
+//Date columns of the append only tables having at least BRINMinRows rows are indexed using IndexMethodBRIN,
+//rest of them are indexed using IndexMethodBTree
+func AutoIndexMethod(dataType string, rows int64, appendOnly bool) string {
+	if dataType == interpreter.DataTypeDate && appendOnly && rows >= BRINMinRows {
+		return IndexMethodBRIN
+	}
+	return IndexMethodBTree
+}
+
+//PlanAutoIndexes compares the indexes on a table with the automatic indexes required on it.
+//It returns the automatic indexes to be created and the existing automatic indexes to be dropped
+//as their columns are no more required to be indexed or they need a different access method.
+//The indexes have to be dropped before creating the new ones as they may have the same name.
+//Indexes not created automatically are left untouched
+func PlanAutoIndexes(existing []IndexInfo, required []AutoIndex) ([]AutoIndex, []IndexInfo) {
+	/*
+	 * We will first find the existing automatic indexes by their column
+	 * Then we will find the indexes to be created and the indexes to be kept
+	 * Finally we will drop the rest of the existing automatic indexes
+	 */
+	//finding the existing automatic indexes
+	current := map[string]IndexInfo{}
+	for _, i := range existing {
+		if i.Auto && len(i.Columns) == 1 {
+			current[i.Columns[0]] = i
+		}
+	}
+
+	//finding the indexes to be created and kept
+	create := []AutoIndex{}
+	kept := map[string]bool{}
+	seen := map[string]bool{}
+	for _, r := range required {
+		if seen[r.Column] {
+			continue
+		}
+		seen[r.Column] = true
+		if i, ok := current[r.Column]; ok && i.Method == r.Method {
+			kept[i.Name] = true
+			continue
+		}
+		create = append(create, r)
+	}
+
+	//dropping the rest of the automatic indexes
+	drop := []IndexInfo{}
+	for _, i := range existing {
+		if i.Auto && !kept[i.Name] {
+			drop = append(drop, i)
+		}
+	}
+	return create, drop
+}
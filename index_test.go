@@ -0,0 +1,50 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestPlanAutoIndexes(t *testing.T) {
+	existing := []toolkit.IndexInfo{
+		{Name: "sales_region_auto_idx", Columns: []string{"region"}, Method: toolkit.IndexMethodBTree, Auto: true},
+		{Name: "sales_sold_on_auto_idx", Columns: []string{"sold on"}, Method: toolkit.IndexMethodBTree, Auto: true},
+		{Name: "sales_city_auto_idx", Columns: []string{"city"}, Method: toolkit.IndexMethodBTree, Auto: true},
+		{Name: "sales_pkey", Columns: []string{"id"}, Method: toolkit.IndexMethodBTree, Primary: true},
+	}
+	required := []toolkit.AutoIndex{
+		{Column: "region", Method: toolkit.IndexMethodBTree},
+		{Column: "sold on", Method: toolkit.IndexMethodBRIN},
+		{Column: "product", Method: toolkit.IndexMethodBTree},
+	}
+	create, drop := toolkit.PlanAutoIndexes(existing, required)
+	expectedCreate := []toolkit.AutoIndex{
+		{Column: "sold on", Method: toolkit.IndexMethodBRIN},
+		{Column: "product", Method: toolkit.IndexMethodBTree},
+	}
+	if !reflect.DeepEqual(create, expectedCreate) {
+		t.Error("expected the indexes to be created", expectedCreate, "got", create)
+		return
+	}
+	expectedDrop := []toolkit.IndexInfo{existing[1], existing[2]}
+	if !reflect.DeepEqual(drop, expectedDrop) {
+		t.Error("expected the indexes to be dropped", expectedDrop, "got", drop)
+		return
+	}
+
+	if m := toolkit.AutoIndexMethod(interpreter.DataTypeDate, toolkit.BRINMinRows, false); m != toolkit.IndexMethodBTree {
+		t.Error("expected btree for the date column of a table that is not append only. got", m)
+		return
+	}
+	if name := toolkit.AutoIndexName(strings.Repeat("t", 60), "region"); len(name) > 63 {
+		t.Error("expected the auto index name to be within 63 characters. got", name)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//DefaultSampleRows is the no. of rows sampled for inferring the schema if not specified in the infer options
+const DefaultSampleRows = 1000
+
+//DefaultDateFormats are the date formats tried while inferring the schema if not specified in the infer options.
+//They are tried in the order so the day first formats are preferred over the month first ones when both match
+var DefaultDateFormats = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"02/01/2006",
+	"01/02/2006",
+	"02-01-2006",
+	"01-02-2006",
+	"02.01.2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02/01/2006 15:04:05",
+	"01/02/2006 15:04:05",
+	"02 Jan 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"January 2, 2006",
+}
+
+//InferOptions has the options for inferring the schema of a file
+type InferOptions struct {
+	//SampleRows is the no. of rows sampled for inferring the schema. Defaults to DefaultSampleRows
+	SampleRows int
+	//DateFormats are the date formats tried for the columns. Defaults to DefaultDateFormats
+	DateFormats []string
+	//Overrides has the columns that override the inferred ones by the name of the inferred column.
+	//The non empty name, data type and date format of an override replace the inferred ones
+	Overrides map[string]interpreter.ColumnNode
+}
+
+//InferredColumn is a column whose data type is inferred from the sampled rows
+type InferredColumn struct {
+	//Column has the name, data type and the date format of the column
+	Column interpreter.ColumnNode
+	//Nullable is true if the column had empty values in the sampled rows
+	Nullable bool
+	//Nulls is the no. of empty values in the sampled rows
+	Nulls int
+}
+
+//Schema is the inferred schema of a file
+type Schema struct {
+	//Columns are the inferred columns in the order of their position in the file
+	Columns []InferredColumn
+	//SampledRows is the no. of rows sampled for inferring the schema
+	SampledRows int
+}
+
+//ColumnNodes returns the columns of the schema that can be used for dumping the file to a datastore
+func (s Schema) ColumnNodes() []interpreter.ColumnNode {
+	columns := make([]interpreter.ColumnNode, len(s.Columns))
+	for i, col := range s.Columns {
+		columns[i] = col.Column
+	}
+	return columns
+}
+
+//Override replaces the inferred columns with the overrides by the name of the inferred column.
+//The non empty name, data type and date format of an override replace the inferred ones
+func (s Schema) Override(overrides map[string]interpreter.ColumnNode) Schema {
+	columns := make([]InferredColumn, len(s.Columns))
+	copy(columns, s.Columns)
+	for i, col := range columns {
+		o, ok := overrides[col.Column.Name]
+		if !ok {
+			continue
+		}
+		if len(o.Name) != 0 {
+			columns[i].Column.Name = o.Name
+		}
+		if len(o.DataType) != 0 {
+			columns[i].Column.DataType = o.DataType
+			columns[i].Column.DateFormat = ""
+		}
+		if len(o.DateFormat) != 0 {
+			columns[i].Column.DateFormat = o.DateFormat
+		}
+	}
+	s.Columns = columns
+	return s
+}
+
+//InferCSVSchema infers the schema of the given csv file written in the dialect by sampling its rows.
+//The names of the columns are taken from the header. If the dialect has no header, they are named by their position like column_1.
+//The data type of a column is the narrowest one among int, float, date and string that all the non empty values match.
+//Compressed files are decompressed while being sampled
+func InferCSVSchema(filename string, dialect CSVDialect, opts InferOptions) (Schema, error) {
+	/*
+	 * We will open the file
+	 * Then we will sample the rows
+	 * Then we will infer the columns from the samples
+	 * Finally we will apply the overrides
+	 */
+	//opening the file
+	r, err := OpenDecompressed(filename, "")
+	if err != nil {
+		return Schema{}, err
+	}
+	defer r.Close()
+	rows, err := NewCSVRowReader(r, dialect)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	//sampling the rows
+	if opts.SampleRows <= 0 {
+		opts.SampleRows = DefaultSampleRows
+	}
+	if len(opts.DateFormats) == 0 {
+		opts.DateFormats = DefaultDateFormats
+	}
+	header := rows.Header()
+	samples := make([]*columnSample, len(header))
+	for i := range samples {
+		samples[i] = newColumnSample(opts.DateFormats)
+	}
+	schema := Schema{}
+	for schema.SampledRows < opts.SampleRows {
+		row, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			//malformed rows are left out of the samples
+			continue
+		}
+		schema.SampledRows++
+		for len(samples) < len(row) {
+			samples = append(samples, newColumnSample(opts.DateFormats))
+			samples[len(samples)-1].nulls = schema.SampledRows - 1
+		}
+		for i, s := range samples {
+			var v interface{}
+			if i < len(row) {
+				v = row[i]
+			}
+			s.add(v)
+		}
+	}
+
+	//inferring the columns
+	names := rows.Columns()
+	seen := map[string]int{}
+	for i, s := range samples {
+		name := "column_" + strconv.Itoa(i+1)
+		if i < len(names) {
+			name = names[i].Name
+		}
+		//duplicate names are suffixed with their count
+		seen[name]++
+		if seen[name] > 1 {
+			name += "_" + strconv.Itoa(seen[name])
+		}
+		col := s.column()
+		col.Column.Name = name
+		schema.Columns = append(schema.Columns, col)
+	}
+
+	//applying the overrides
+	return schema.Override(opts.Overrides), nil
+}
+
+//IngestCSV infers the schema of the given csv file and dumps it to the datastore as per the dump options.
+//The overrides in the infer options are applied to the inferred schema before dumping. It returns the schema used for the dump
+func IngestCSV(d Datastore, filename string, tablename string, infer InferOptions, opts DumpOptions, logger log.Log) (Schema, DumpResult, error) {
+	logger.Info("inferring the schema of the csv file", filename)
+	schema, err := InferCSVSchema(filename, opts.Dialect, infer)
+	if err != nil {
+		logger.Error("error while inferring the schema of the csv file", filename)
+		return schema, DumpResult{}, err
+	}
+	logger.Info("inferred the schema of the csv file", filename, "having no. of columns:-", len(schema.Columns), "from no. of rows:-", schema.SampledRows)
+	result, err := d.DumpCSVWithOptions(filename, tablename, schema.ColumnNodes(), opts, logger)
+	return schema, result, err
+}
+
+//columnSample keeps track of the data types matching the sampled values of a column
+type columnSample struct {
+	values      int
+	nulls       int
+	isInt       bool
+	isFloat     bool
+	dateFormats []string
+}
+
+//newColumnSample returns the sample of a column for which all the data types and the date formats are possible
+func newColumnSample(dateFormats []string) *columnSample {
+	return &columnSample{isInt: true, isFloat: true, dateFormats: append([]string(nil), dateFormats...)}
+}
+
+//add adds a value to the sample ruling out the data types and the date formats that the value doesn't match
+func (c *columnSample) add(v interface{}) {
+	str, null := FormatValue(v)
+	str = strings.TrimSpace(str)
+	if null || len(str) == 0 {
+		c.nulls++
+		return
+	}
+	c.values++
+	if c.isInt {
+		_, err := strconv.ParseInt(str, 10, 64)
+		//values with leading zeros like zip codes are not numbers
+		c.isInt = err == nil && !hasLeadingZero(str)
+	}
+	if c.isFloat {
+		_, err := strconv.ParseFloat(str, 64)
+		c.isFloat = err == nil && !hasLeadingZero(str)
+	}
+	formats := c.dateFormats[:0]
+	for _, f := range c.dateFormats {
+		if _, err := time.Parse(f, str); err == nil {
+			formats = append(formats, f)
+		}
+	}
+	c.dateFormats = formats
+}
+
+//column returns the inferred column for the sample. Columns without any values are inferred as string
+func (c *columnSample) column() InferredColumn {
+	col := InferredColumn{Nullable: c.nulls > 0, Nulls: c.nulls}
+	switch {
+	case c.values == 0:
+		col.Column.DataType = interpreter.DataTypeString
+	case c.isInt:
+		col.Column.DataType = interpreter.DataTypeInt
+	case c.isFloat:
+		col.Column.DataType = interpreter.DataTypeFloat
+	case len(c.dateFormats) != 0:
+		col.Column.DataType = interpreter.DataTypeDate
+		col.Column.DateFormat = c.dateFormats[0]
+	default:
+		col.Column.DataType = interpreter.DataTypeString
+	}
+	return col
+}
+
+//hasLeadingZero returns true if the number has a leading zero that is not followed by a decimal point
+func hasLeadingZero(str string) bool {
+	str = strings.TrimLeft(str, "+-")
+	return len(str) > 1 && str[0] == '0' && str[1] != '.'
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestInferCSVSchema(t *testing.T) {
+	f, err := ioutil.TempFile("", "cuttle-infer-*.csv")
+	if err != nil {
+		t.Error("error while creating the test file", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("id,price,sold on,zip,name,name,notes\n" +
+		"1,10,2019-12-01,01234,apple,red,\n" +
+		"2,2.5,2019-12-31,56789,pear,,\n" +
+		"3,,2020-01-15,10001,fig,green,\n" +
+		"4,1e3,not a date,10002,kiwi,brown,\n")
+	f.Close()
+
+	schema, err := toolkit.InferCSVSchema(f.Name(), toolkit.CSVDialect{}, toolkit.InferOptions{
+		SampleRows: 3,
+		Overrides:  map[string]interpreter.ColumnNode{"notes": {Name: "remarks"}},
+	})
+	if err != nil {
+		t.Error("error while inferring the schema", err)
+		return
+	}
+	expected := []toolkit.InferredColumn{
+		{Column: interpreter.ColumnNode{Name: "id", DataType: interpreter.DataTypeInt}},
+		{Column: interpreter.ColumnNode{Name: "price", DataType: interpreter.DataTypeFloat}, Nullable: true, Nulls: 1},
+		{Column: interpreter.ColumnNode{Name: "sold on", DataType: interpreter.DataTypeDate, DateFormat: "2006-01-02"}},
+		{Column: interpreter.ColumnNode{Name: "zip", DataType: interpreter.DataTypeString}},
+		{Column: interpreter.ColumnNode{Name: "name", DataType: interpreter.DataTypeString}},
+		{Column: interpreter.ColumnNode{Name: "name_2", DataType: interpreter.DataTypeString}, Nullable: true, Nulls: 1},
+		{Column: interpreter.ColumnNode{Name: "remarks", DataType: interpreter.DataTypeString}, Nullable: true, Nulls: 3},
+	}
+	if schema.SampledRows != 3 || !reflect.DeepEqual(schema.Columns, expected) {
+		t.Error("expected the schema", expected, "from 3 rows. got", schema.Columns, "from", schema.SampledRows, "rows")
+	}
+}
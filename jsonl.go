@@ -0,0 +1,157 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//JSONPathSeparator joins the keys of the nested objects in a json document to get the name of the column they map to.
+//For example {"user": {"id": 1}} maps to the column user.id
+const JSONPathSeparator = "."
+
+//JSONParseError is the error when a line in a json lines source isn't a valid json object
+type JSONParseError struct {
+	//Line is the line number of the malformed line
+	Line int
+	//Err is the error while decoding the line
+	Err error
+}
+
+//Error returns the error message with the line number
+func (j *JSONParseError) Error() string {
+	return "couldn't decode the json object at line " + strconv.Itoa(j.Line) + ": " + j.Err.Error()
+}
+
+//Unwrap returns the error while decoding the line
+func (j *JSONParseError) Unwrap() error {
+	return j.Err
+}
+
+//JSONLReader reads the rows from a newline delimited json source.
+//Each line is a json object whose keys are mapped to the columns with the nested objects flattened using JSONPathSeparator
+type JSONLReader struct {
+	r        *bufio.Reader
+	columns  map[string]int
+	overflow bool
+	n        int
+	line     int
+	start    int
+	raw      string
+}
+
+//NewJSONLReader returns a reader reading the rows for the columns from the json lines in r.
+//If overflow is set, each row has an additional value at the end having the structure not mapped to the columns as json
+func NewJSONLReader(r io.Reader, columns []interpreter.ColumnNode, overflow bool) *JSONLReader {
+	colIndex := map[string]int{}
+	for i, col := range columns {
+		colIndex[col.Name] = i
+	}
+	n := len(columns)
+	if overflow {
+		n++
+	}
+	return &JSONLReader{r: bufio.NewReader(r), columns: colIndex, overflow: overflow, n: n}
+}
+
+//Line returns the line number at which the last read row started
+func (j *JSONLReader) Line() int {
+	return j.start
+}
+
+//Raw returns the text of the last read line without the surrounding whitespaces. It is available for the malformed lines too
+func (j *JSONLReader) Raw() string {
+	return j.raw
+}
+
+//Read reads the next row from the json lines. Keys that are missing in the object are nulls.
+//Objects and arrays mapped to a column are stored as json. It returns io.EOF when there are no more rows
+//and a *JSONParseError for a malformed line after which the reading can continue with the next line
+func (j *JSONLReader) Read() ([]interface{}, error) {
+	/*
+	 * We will skip the empty lines
+	 * Then we will decode the object in the line
+	 * Then we will flatten the object to the columns
+	 * If required we will add the structure not mapped to the columns
+	 */
+	//skipping the empty lines
+	var line []byte
+	for len(line) == 0 {
+		l, err := j.r.ReadBytes('\n')
+		if len(l) == 0 && err != nil {
+			return nil, err
+		}
+		j.line++
+		line = bytes.TrimSpace(l)
+	}
+	j.start = j.line
+	j.raw = string(line)
+
+	//decoding the object
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	obj := map[string]interface{}{}
+	if err := d.Decode(&obj); err != nil {
+		return nil, &JSONParseError{Line: j.line, Err: err}
+	}
+
+	//flattening the object
+	row := make([]interface{}, j.n)
+	leftover := j.flatten("", obj, row)
+
+	//adding the structure not mapped to the columns
+	if j.overflow && len(leftover) != 0 {
+		b, err := json.Marshal(leftover)
+		if err != nil {
+			return nil, err
+		}
+		row[j.n-1] = string(b)
+	}
+	return row, nil
+}
+
+//flatten sets the values in the object that map to the columns in the row.
+//It returns the structure of the object that couldn't be mapped to the columns
+func (j *JSONLReader) flatten(prefix string, obj map[string]interface{}, row []interface{}) map[string]interface{} {
+	leftover := map[string]interface{}{}
+	for k, v := range obj {
+		path := prefix + k
+		if i, ok := j.columns[path]; ok {
+			row[i] = jsonValue(v)
+			continue
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			if l := j.flatten(path+JSONPathSeparator, child, row); len(l) != 0 {
+				leftover[k] = l
+			}
+			continue
+		}
+		leftover[k] = v
+	}
+	return leftover
+}
+
+//jsonValue converts a decoded json value to the value that can be stored in a column
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
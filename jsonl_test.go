@@ -0,0 +1,74 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestJSONLReader(t *testing.T) {
+	src := `{"event": "click", "user": {"id": 7, "name": "ann"}, "tags": ["a"]}
+
+{"event": "view", "meta": {"ip": "1.1.1.1"}}
+`
+	r := toolkit.NewJSONLReader(strings.NewReader(src), []interpreter.ColumnNode{
+		{Name: "event"},
+		{Name: "user.id", DataType: interpreter.DataTypeInt},
+	}, true)
+	expected := [][]interface{}{
+		{"click", "7", `{"tags":["a"],"user":{"name":"ann"}}`},
+		{"view", nil, `{"meta":{"ip":"1.1.1.1"}}`},
+	}
+	lines := []int{1, 3}
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			if i != len(expected) {
+				t.Error("expected", len(expected), "rows. got", i)
+			}
+			return
+		}
+		if err != nil {
+			t.Error("error while reading the row", err)
+			return
+		}
+		if !reflect.DeepEqual(row, expected[i]) || r.Line() != lines[i] {
+			t.Error("expected", expected[i], "at line", lines[i], "got", row, "at line", r.Line())
+		}
+	}
+}
+
+func TestJSONLReaderMalformedLine(t *testing.T) {
+	src := `{"event": "click"}
+{"event"
+{"event": "view"}
+`
+	r := toolkit.NewJSONLReader(strings.NewReader(src), []interpreter.ColumnNode{{Name: "event"}}, false)
+	if _, err := r.Read(); err != nil {
+		t.Error("error while reading the first row", err)
+		return
+	}
+	_, err := r.Read()
+	pErr, ok := err.(*toolkit.JSONParseError)
+	if !ok || pErr.Line != 2 {
+		t.Error("expected a json parse error at line 2. got", err)
+		return
+	}
+	if r.Raw() != `{"event"` {
+		t.Error("expected the text of the malformed line. got", r.Raw())
+		return
+	}
+	row, err := r.Read()
+	if err != nil || !reflect.DeepEqual(row, []interface{}{"view"}) {
+		t.Error("expected the reading to continue after the malformed line. got", row, err)
+		return
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import "time"
+
+const (
+	//OperationExec is the operation of running a query
+	OperationExec = "exec"
+	//OperationDumpCSV is the operation of dumping a csv file
+	OperationDumpCSV = "dump_csv"
+	//OperationDumpCSVArchive is the operation of dumping the csv files in an archive
+	OperationDumpCSVArchive = "dump_csv_archive"
+	//OperationDumpCSVResumable is the operation of dumping a csv file in chunks
+	OperationDumpCSVResumable = "dump_csv_resumable"
+	//OperationDumpRows is the operation of dumping the rows from a reader
+	OperationDumpRows = "dump_rows"
+	//OperationDumpJSONL is the operation of dumping a json lines file
+	OperationDumpJSONL = "dump_jsonl"
+	//OperationDumpParquet is the operation of dumping a parquet file
+	OperationDumpParquet = "dump_parquet"
+	//OperationDumpExcel is the operation of dumping an excel workbook
+	OperationDumpExcel = "dump_excel"
+	//OperationChangeColumnTypeToDate is the operation of changing the data type of a column to date
+	OperationChangeColumnTypeToDate = "change_column_type_to_date"
+	//OperationIdentifyDimensions is the step of the dataset optimizer identifying the dimensions
+	OperationIdentifyDimensions = "identify_dimensions"
+	//OperationConvertDates is the step of the dataset optimizer converting the date columns
+	OperationConvertDates = "convert_dates"
+	//OperationIndexDataset is the step of the dataset optimizer indexing the dimensions and the default date field
+	OperationIndexDataset = "index_dataset"
+)
+
+//Event has the info about an operation on a datastore
+type Event struct {
+	//Operation is the type of the operation like OperationExec
+	Operation string
+	//Table is the table operated on. It is empty for the operations not specific to a table like running a query
+	Table string
+	//Phase is the phase of the dump if the event is of a phase of a dump operation
+	Phase Phase
+	//Start is the time at which the operation started
+	Start time.Time
+	//Duration is the time taken by the operation. It is set only when the operation has finished
+	Duration time.Duration
+	//Rows is the no. of rows affected, loaded or returned by the operation. It is set only when the operation has finished
+	Rows int64
+	//Err is the error with which the operation failed. It is set only when the operation has finished
+	Err error
+}
+
+//Observer observes the operations on the datastores. It is called from the goroutine doing the operation so it should return quickly
+type Observer interface {
+	//OperationStarted is called when an operation starts
+	OperationStarted(e Event)
+	//OperationFinished is called when an operation finishes
+	OperationFinished(e Event)
+}
+
+//Observers reports the operations to all the observers in it
+type Observers []Observer
+
+//OperationStarted reports the start of the operation to all the observers
+func (o Observers) OperationStarted(e Event) {
+	for _, ob := range o {
+		ob.OperationStarted(e)
+	}
+}
+
+//OperationFinished reports the end of the operation to all the observers
+func (o Observers) OperationFinished(e Event) {
+	for _, ob := range o {
+		ob.OperationFinished(e)
+	}
+}
+
+//Span is an operation being observed
+type Span struct {
+	o Observer
+	e Event
+}
+
+//StartOperation reports the start of an operation to the observer and returns its span for reporting its end.
+//The observer can be nil in which case nothing is reported
+func StartOperation(o Observer, operation string, tablename string) *Span {
+	return startSpan(o, Event{Operation: operation, Table: tablename})
+}
+
+//startSpan starts the span of the event
+func startSpan(o Observer, e Event) *Span {
+	e.Start = time.Now()
+	if o != nil {
+		o.OperationStarted(e)
+	}
+	return &Span{o: o, e: e}
+}
+
+//Finish reports the end of the operation with the no. of rows affected and the error if it failed
+func (s *Span) Finish(rows int64, err error) {
+	if s.o == nil {
+		return
+	}
+	s.e.Duration = time.Since(s.e.Start)
+	s.e.Rows = rows
+	s.e.Err = err
+	s.o.OperationFinished(s.e)
+}
+
+//ObservePhases returns the dump options that report each phase of the dump to the observer as an operation.
+//The phases are found from the progress reported by the dump, which is passed on to the progress func of the options.
+//The returned func has to be called with the error of the dump once it ends to finish the last phase
+func ObservePhases(o Observer, operation string, tablename string, opts DumpOptions) (DumpOptions, func(err error)) {
+	if o == nil {
+		return opts, func(error) {}
+	}
+	var span *Span
+	var rows int64
+	progress := opts.Progress
+	opts.Progress = func(p Progress) {
+		if span == nil || span.e.Phase != p.Phase {
+			if span != nil {
+				span.Finish(rows, nil)
+			}
+			span = startSpan(o, Event{Operation: operation, Table: tablename, Phase: p.Phase})
+		}
+		rows = p.Rows
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return opts, func(err error) {
+		if span != nil {
+			span.Finish(rows, err)
+			span = nil
+		}
+	}
+}
+
+//ObserveDump runs the dump reporting it along with its phases to the observer. The dump is reported with the no. of rows loaded.
+//The observer can be nil in which case the dump is run as it is
+func ObserveDump(o Observer, operation string, tablename string, opts DumpOptions, dump func(opts DumpOptions) (DumpResult, error)) (DumpResult, error) {
+	span := StartOperation(o, operation, tablename)
+	opts, finish := ObservePhases(o, operation, tablename, opts)
+	result, err := dump(opts)
+	finish(err)
+	span.Finish(result.RowsLoaded, err)
+	return result, err
+}
+
+//ObserveDumps runs the dump to many tables reporting it along with its phases to the observer.
+//The dump is reported with the total no. of rows loaded to the tables. The observer can be nil in which case the dump is run as it is
+func ObserveDumps(o Observer, operation string, tablename string, opts DumpOptions, dump func(opts DumpOptions) (map[string]DumpResult, error)) (map[string]DumpResult, error) {
+	span := StartOperation(o, operation, tablename)
+	opts, finish := ObservePhases(o, operation, tablename, opts)
+	results, err := dump(opts)
+	finish(err)
+	var rows int64
+	for _, r := range results {
+		rows += r.RowsLoaded
+	}
+	span.Finish(rows, err)
+	return results, err
+}
@@ -0,0 +1,420 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+	"github.com/xitongsys/parquet-go/marshal"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+//ParquetReader reads the rows from a parquet file. The rows are read one row group at a time.
+//Nested fields are flattened to columns with their path joined using JSONPathSeparator. Repeated fields are not supported
+type ParquetReader struct {
+	f        *parquetFile
+	pr       *reader.ParquetReader
+	columns  []interpreter.ColumnNode
+	elements []*parquet.SchemaElement
+	//selected has the index of the parquet column for each of the selected columns. -1 if the column is not in the file
+	selected []int
+	//rowGroup is the index of the next row group to be read
+	rowGroup int
+	//values has the values of the columns in the current row group
+	values [][]interface{}
+	next   int
+	row    int
+}
+
+//NewParquetReader returns a reader reading the rows from the given parquet file
+func NewParquetReader(filename string) (*ParquetReader, error) {
+	/*
+	 * We will open the file and read the footer
+	 * Then we will map the leaf fields in the schema to the columns
+	 */
+	//opening the file
+	f, err := openParquetFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := reader.NewParquetColumnReader(f, 1)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	//mapping the fields to the columns
+	p := &ParquetReader{f: f, pr: pr}
+	sh := pr.SchemaHandler
+	for _, path := range sh.ValueColumns {
+		e := sh.SchemaElements[sh.MapIndex[path]]
+		if e.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			pr.ReadStop()
+			f.Close()
+			return nil, errors.New("repeated field " + path + " in the parquet file is not supported")
+		}
+		exPath := strings.Split(sh.InPathToExPath[path], ".")[1:]
+		p.columns = append(p.columns, interpreter.ColumnNode{
+			Name:     strings.Join(exPath, JSONPathSeparator),
+			DataType: parquetDataType(e),
+		})
+		p.elements = append(p.elements, e)
+	}
+	p.Select(p.columns)
+	return p, nil
+}
+
+//Columns returns the columns in the parquet file with their data types mapped from the parquet logical types
+func (p *ParquetReader) Columns() []interpreter.ColumnNode {
+	return p.columns
+}
+
+//Select sets the columns to be read by the reader. The rows are read in the order of the given columns.
+//Columns not present in the file are read as nulls
+func (p *ParquetReader) Select(columns []interpreter.ColumnNode) {
+	colIndex := map[string]int{}
+	for i, col := range p.columns {
+		colIndex[col.Name] = i
+	}
+	p.selected = make([]int, len(columns))
+	for i, col := range columns {
+		p.selected[i] = -1
+		if j, ok := colIndex[col.Name]; ok {
+			p.selected[i] = j
+		}
+	}
+}
+
+//NumRows returns the total no. of rows in the parquet file
+func (p *ParquetReader) NumRows() int64 {
+	return p.pr.GetNumRows()
+}
+
+//Line returns the row number of the last read row
+func (p *ParquetReader) Line() int {
+	return p.row
+}
+
+//Read reads the next row from the parquet file. It returns io.EOF when there are no more rows
+func (p *ParquetReader) Read() ([]interface{}, error) {
+	//reading the next row group if the current one is exhausted
+	for p.values == nil || p.next >= len(p.values[0]) {
+		if p.rowGroup >= len(p.pr.Footer.RowGroups) {
+			return nil, io.EOF
+		}
+		if err := p.readRowGroup(); err != nil {
+			return nil, err
+		}
+	}
+
+	row := make([]interface{}, len(p.selected))
+	for i, j := range p.selected {
+		if j >= 0 {
+			row[i] = p.values[j][p.next]
+		}
+	}
+	p.next++
+	p.row++
+	return row, nil
+}
+
+func (p *ParquetReader) readRowGroup() error {
+	num := p.pr.Footer.RowGroups[p.rowGroup].GetNumRows()
+	p.rowGroup++
+	p.next = 0
+	p.values = make([][]interface{}, len(p.columns))
+	if len(p.columns) == 0 {
+		p.values = [][]interface{}{{}}
+		return nil
+	}
+	for i, e := range p.elements {
+		values, _, _, err := p.pr.ReadColumnByIndex(int64(i), num)
+		if err != nil {
+			return err
+		}
+		if int64(len(values)) != num {
+			return errors.New("expected " + strconv.FormatInt(num, 10) + " values in the row group for the column " + p.columns[i].Name + ". got " + strconv.Itoa(len(values)))
+		}
+		for k, v := range values {
+			values[k] = parquetValue(v, e)
+		}
+		p.values[i] = values
+	}
+	return nil
+}
+
+//Close closes the parquet file along with the handles opened by the column reader for each column
+func (p *ParquetReader) Close() error {
+	p.pr.ReadStop()
+	return p.f.Close()
+}
+
+//parquetDataType returns the interpreter data type for the logical type of a parquet field
+func parquetDataType(e *parquet.SchemaElement) string {
+	if e.IsSetConvertedType() {
+		switch e.GetConvertedType() {
+		case parquet.ConvertedType_DATE, parquet.ConvertedType_TIMESTAMP_MILLIS, parquet.ConvertedType_TIMESTAMP_MICROS:
+			return interpreter.DataTypeDate
+		case parquet.ConvertedType_DECIMAL:
+			return interpreter.DataTypeFloat
+		case parquet.ConvertedType_INT_8, parquet.ConvertedType_INT_16, parquet.ConvertedType_INT_32, parquet.ConvertedType_INT_64,
+			parquet.ConvertedType_UINT_8, parquet.ConvertedType_UINT_16, parquet.ConvertedType_UINT_32, parquet.ConvertedType_UINT_64:
+			return interpreter.DataTypeInt
+		case parquet.ConvertedType_UTF8, parquet.ConvertedType_ENUM, parquet.ConvertedType_JSON:
+			return interpreter.DataTypeString
+		}
+	}
+	if e.IsSetLogicalType() {
+		lt := e.GetLogicalType()
+		if lt.IsSetDATE() || lt.IsSetTIMESTAMP() {
+			return interpreter.DataTypeDate
+		}
+		if lt.IsSetDECIMAL() {
+			return interpreter.DataTypeFloat
+		}
+	}
+	switch e.GetType() {
+	case parquet.Type_INT32, parquet.Type_INT64:
+		return interpreter.DataTypeInt
+	case parquet.Type_FLOAT, parquet.Type_DOUBLE:
+		return interpreter.DataTypeFloat
+	case parquet.Type_INT96:
+		return interpreter.DataTypeDate
+	}
+	return interpreter.DataTypeString
+}
+
+//parquetValue converts a value read from a parquet field to the value that can be stored in a column
+func parquetValue(v interface{}, e *parquet.SchemaElement) interface{} {
+	if v == nil {
+		return nil
+	}
+	ct := parquet.ConvertedType(-1)
+	if e.IsSetConvertedType() {
+		ct = e.GetConvertedType()
+	}
+	lt := e.GetLogicalType()
+	switch val := v.(type) {
+	case bool:
+		return strconv.FormatBool(val)
+	case int32:
+		if ct == parquet.ConvertedType_DATE || (lt != nil && lt.IsSetDATE()) {
+			return time.Unix(int64(val)*24*60*60, 0).UTC()
+		}
+		if ct == parquet.ConvertedType_DECIMAL {
+			return float64(val) / math.Pow10(int(e.GetScale()))
+		}
+		return int64(val)
+	case int64:
+		switch {
+		case ct == parquet.ConvertedType_TIMESTAMP_MILLIS || (lt != nil && lt.IsSetTIMESTAMP() && lt.GetTIMESTAMP().GetUnit().IsSetMILLIS()):
+			return time.Unix(0, val*int64(time.Millisecond)).UTC()
+		case ct == parquet.ConvertedType_TIMESTAMP_MICROS || (lt != nil && lt.IsSetTIMESTAMP() && lt.GetTIMESTAMP().GetUnit().IsSetMICROS()):
+			return time.Unix(0, val*int64(time.Microsecond)).UTC()
+		case lt != nil && lt.IsSetTIMESTAMP():
+			return time.Unix(0, val).UTC()
+		case ct == parquet.ConvertedType_DECIMAL:
+			return float64(val) / math.Pow10(int(e.GetScale()))
+		}
+		return val
+	case float32:
+		return float64(val)
+	case string:
+		if e.GetType() == parquet.Type_INT96 && len(val) == 12 {
+			//int96 timestamps have the nano seconds of the day followed by the julian day
+			nanos := int64(binary.LittleEndian.Uint64([]byte(val[:8])))
+			days := int64(binary.LittleEndian.Uint32([]byte(val[8:])))
+			return time.Unix((days-2440588)*24*60*60, nanos).UTC()
+		}
+		if ct == parquet.ConvertedType_DECIMAL {
+			//decimals stored as bytes are big endian two's complement integers
+			n := new(big.Int).SetBytes([]byte(val))
+			if len(val) > 0 && val[0]&0x80 != 0 {
+				n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(val)*8)))
+			}
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return f / math.Pow10(int(e.GetScale()))
+		}
+		return val
+	}
+	return v
+}
+
+//ParquetWriter writes the rows to a parquet file. The rows are flushed as row groups while writing
+type ParquetWriter struct {
+	f       *parquetFile
+	pw      *writer.ParquetWriter
+	columns []Column
+}
+
+//NewParquetWriter returns a writer writing the rows with the given columns to w in the parquet format.
+//All the columns are optional with dates written as timestamps in milliseconds
+func NewParquetWriter(w io.Writer, columns []Column) (*ParquetWriter, error) {
+	/*
+	 * We will build the schema from the columns
+	 * Then we will create the writer
+	 */
+	//building the schema
+	root := parquet.NewSchemaElement()
+	root.Name = "schema"
+	numChildren := int32(len(columns))
+	root.NumChildren = &numChildren
+	root.RepetitionType = parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_REQUIRED)
+	elements := []*parquet.SchemaElement{root}
+	for _, col := range columns {
+		e := parquet.NewSchemaElement()
+		e.Name = col.Name
+		e.RepetitionType = parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL)
+		switch col.DataType {
+		case interpreter.DataTypeInt:
+			e.Type = parquet.TypePtr(parquet.Type_INT64)
+		case interpreter.DataTypeFloat:
+			e.Type = parquet.TypePtr(parquet.Type_DOUBLE)
+		case interpreter.DataTypeDate:
+			e.Type = parquet.TypePtr(parquet.Type_INT64)
+			e.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_TIMESTAMP_MILLIS)
+		default:
+			e.Type = parquet.TypePtr(parquet.Type_BYTE_ARRAY)
+			e.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8)
+		}
+		elements = append(elements, e)
+	}
+
+	//creating the writer
+	f := &parquetFile{w: w}
+	pw, err := writer.NewParquetWriter(f, elements, 4)
+	if err != nil {
+		return nil, err
+	}
+	pw.MarshalFunc = marshal.MarshalCSV
+	return &ParquetWriter{f: f, pw: pw, columns: columns}, nil
+}
+
+//Write writes a row to the parquet file. The values in the row are converted to the data types of the columns
+func (p *ParquetWriter) Write(row []interface{}) error {
+	if len(row) != len(p.columns) {
+		return errors.New("expected " + strconv.Itoa(len(p.columns)) + " values in the row. got " + strconv.Itoa(len(row)))
+	}
+	rec := make([]interface{}, len(row))
+	for i, v := range row {
+		val, err := toParquetValue(v, p.columns[i].DataType)
+		if err != nil {
+			return errors.New("couldn't write the value of the column " + p.columns[i].Name + ": " + err.Error())
+		}
+		rec[i] = val
+	}
+	return p.pw.Write(rec)
+}
+
+//Close flushes the pending rows and writes the footer of the parquet file. It won't close the underlying writer
+func (p *ParquetWriter) Close() error {
+	return p.pw.WriteStop()
+}
+
+//toParquetValue converts a value to the go type written for the parquet field of the data type
+func toParquetValue(v interface{}, dataType string) (interface{}, error) {
+	str, null := FormatValue(v)
+	if null {
+		return nil, nil
+	}
+	switch dataType {
+	case interpreter.DataTypeInt:
+		switch val := v.(type) {
+		case int64:
+			return val, nil
+		case int32:
+			return int64(val), nil
+		case int:
+			return int64(val), nil
+		}
+		return strconv.ParseInt(str, 10, 64)
+	case interpreter.DataTypeFloat:
+		switch val := v.(type) {
+		case float64:
+			return val, nil
+		case float32:
+			return float64(val), nil
+		}
+		return strconv.ParseFloat(str, 64)
+	case interpreter.DataTypeDate:
+		t, ok := v.(time.Time)
+		if !ok {
+			var err error
+			if t, err = time.Parse(time.RFC3339Nano, str); err != nil {
+				if t, err = time.Parse("2006-01-02", str); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return t.UnixNano() / int64(time.Millisecond), nil
+	}
+	return str, nil
+}
+
+//parquetFile implements the source.ParquetFile for reading a local file or writing to an io.Writer
+type parquetFile struct {
+	name string
+	f    *os.File
+	w    io.Writer
+}
+
+func openParquetFile(name string) (*parquetFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetFile{name: name, f: f}, nil
+}
+
+func (p *parquetFile) Open(name string) (source.ParquetFile, error) {
+	if len(name) == 0 {
+		name = p.name
+	}
+	return openParquetFile(name)
+}
+
+func (p *parquetFile) Create(name string) (source.ParquetFile, error) {
+	return nil, errors.New("parquet file can't be created")
+}
+
+func (p *parquetFile) Seek(offset int64, whence int) (int64, error) {
+	if p.f == nil {
+		return 0, errors.New("parquet file opened for writing can't be seeked")
+	}
+	return p.f.Seek(offset, whence)
+}
+
+func (p *parquetFile) Read(b []byte) (int, error) {
+	if p.f == nil {
+		return 0, errors.New("parquet file opened for writing can't be read")
+	}
+	return p.f.Read(b)
+}
+
+func (p *parquetFile) Write(b []byte) (int, error) {
+	if p.w == nil {
+		return 0, errors.New("parquet file opened for reading can't be written")
+	}
+	return p.w.Write(b)
+}
+
+func (p *parquetFile) Close() error {
+	if p.f == nil {
+		return nil
+	}
+	return p.f.Close()
+}
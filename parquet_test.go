@@ -0,0 +1,86 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestParquetRoundTrip(t *testing.T) {
+	f, err := ioutil.TempFile("", "groceries-*.parquet")
+	if err != nil {
+		t.Error("error while creating the test parquet file", err)
+		return
+	}
+	defer os.Remove(f.Name())
+
+	columns := []toolkit.Column{
+		{Name: "item", DataType: interpreter.DataTypeString},
+		{Name: "quantity", DataType: interpreter.DataTypeInt},
+		{Name: "price", DataType: interpreter.DataTypeFloat},
+		{Name: "bought_on", DataType: interpreter.DataTypeDate},
+	}
+	day := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+	rows := [][]interface{}{
+		{"apple", int64(3), 1.5, day},
+		{"banana", nil, "2.25", nil},
+	}
+	w, err := toolkit.NewParquetWriter(f, columns)
+	if err != nil {
+		t.Error("error while creating the parquet writer", err)
+		return
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Error("error while writing the row", err)
+			return
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Error("error while closing the parquet writer", err)
+		return
+	}
+	f.Close()
+
+	r, err := toolkit.NewParquetReader(f.Name())
+	if err != nil {
+		t.Error("error while opening the parquet file", err)
+		return
+	}
+	defer r.Close()
+	for i, col := range r.Columns() {
+		if col.Name != columns[i].Name || col.DataType != columns[i].DataType {
+			t.Error("expected the column", columns[i], "got", col)
+		}
+	}
+	expected := [][]interface{}{
+		{"apple", int64(3), 1.5, day},
+		{"banana", nil, 2.25, nil},
+	}
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			if i != len(expected) {
+				t.Error("expected", len(expected), "rows. got", i)
+			}
+			return
+		}
+		if err != nil {
+			t.Error("error while reading the row", err)
+			return
+		}
+		if !reflect.DeepEqual(row, expected[i]) {
+			t.Error("expected", expected[i], "got", row)
+		}
+	}
+}
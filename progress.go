@@ -0,0 +1,149 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"context"
+	"io"
+)
+
+//Phase is a phase of dumping data to a datastore
+type Phase string
+
+const (
+	//PhaseValidate is the phase in which the rows in the file are validated and rewritten if required
+	PhaseValidate Phase = "validate"
+	//PhaseTransfer is the phase in which the file is transferred to the datastore server
+	PhaseTransfer Phase = "transfer"
+	//PhaseCreate is the phase in which the table is created or prepared for the data
+	PhaseCreate Phase = "create"
+	//PhaseCopy is the phase in which the data is copied to the table
+	PhaseCopy Phase = "copy"
+	//PhaseCleanup is the phase in which the loaded data is finalized and the temporary files are removed
+	PhaseCleanup Phase = "cleanup"
+)
+
+//ProgressInterval is the no. of rows after which the progress is reported while copying the rows
+const ProgressInterval = 10000
+
+//ProgressBytesInterval is the no. of bytes after which the progress is reported while reading the source
+const ProgressBytesInterval = 1 << 20
+
+//Progress has the progress of dumping data to a datastore
+type Progress struct {
+	//Phase is the current phase of the dump
+	Phase Phase
+	//Bytes is the no. of bytes of the source processed in the current phase
+	Bytes int64
+	//TotalBytes is the size of the source in bytes. It is zero if not known
+	TotalBytes int64
+	//Rows is the no. of rows processed in the current phase
+	Rows int64
+	//TotalRows is the no. of rows in the source. It is zero if not known
+	TotalRows int64
+}
+
+//ProgressFunc is called with the progress of a dump. It is called from the goroutine doing the dump so it should return quickly
+type ProgressFunc func(Progress)
+
+//ProgressTracker tracks the progress of a dump and reports it to the progress func in the dump options.
+//It also checks whether the dump has been cancelled using the context in the dump options
+type ProgressTracker struct {
+	ctx           context.Context
+	fn            ProgressFunc
+	progress      Progress
+	reported      int64
+	reportedBytes int64
+}
+
+//NewProgressTracker returns the progress tracker for the dump options
+func NewProgressTracker(opts DumpOptions) *ProgressTracker {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &ProgressTracker{ctx: ctx, fn: opts.Progress}
+}
+
+//Context returns the context of the dump. The dump is to be aborted once it is done
+func (p *ProgressTracker) Context() context.Context {
+	return p.ctx
+}
+
+//Progress returns the current progress of the dump
+func (p *ProgressTracker) Progress() Progress {
+	return p.progress
+}
+
+//SetTotal sets the size of the source in bytes and the no. of rows in it. They are zero if not known
+func (p *ProgressTracker) SetTotal(bytes int64, rows int64) {
+	p.progress.TotalBytes = bytes
+	p.progress.TotalRows = rows
+}
+
+//Phase starts a new phase of the dump and reports it. It returns an error if the dump has been cancelled
+func (p *ProgressTracker) Phase(phase Phase) error {
+	p.progress.Phase = phase
+	p.progress.Bytes = 0
+	p.progress.Rows = 0
+	p.reported = 0
+	p.reportedBytes = 0
+	p.report()
+	return p.ctx.Err()
+}
+
+//AddBytes adds the no. of bytes processed in the current phase. The progress is reported every ProgressBytesInterval bytes
+func (p *ProgressTracker) AddBytes(n int64) {
+	p.progress.Bytes += n
+	if p.progress.Bytes-p.reportedBytes >= ProgressBytesInterval {
+		p.reportedBytes = p.progress.Bytes
+		p.report()
+	}
+}
+
+//AddRows adds the no. of rows processed in the current phase. The progress is reported every ProgressInterval rows.
+//It returns an error if the dump has been cancelled
+func (p *ProgressTracker) AddRows(n int64) error {
+	p.progress.Rows += n
+	if p.progress.Rows-p.reported >= ProgressInterval {
+		p.reported = p.progress.Rows
+		p.report()
+	}
+	return p.ctx.Err()
+}
+
+//Done reports the progress at the end of the current phase
+func (p *ProgressTracker) Done() {
+	p.reported = p.progress.Rows
+	p.reportedBytes = p.progress.Bytes
+	p.report()
+}
+
+//Reader returns a reader that adds the bytes read from r to the progress.
+//The reads fail once the dump has been cancelled
+func (p *ProgressTracker) Reader(r io.Reader) io.Reader {
+	return &progressReader{r: r, p: p}
+}
+
+func (p *ProgressTracker) report() {
+	if p.fn != nil {
+		p.fn(p.progress)
+	}
+}
+
+//progressReader adds the bytes read to the progress of a dump
+type progressReader struct {
+	r io.Reader
+	p *ProgressTracker
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	if err := r.p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(b)
+	r.p.AddBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestProgressTracker(t *testing.T) {
+	reports := []toolkit.Progress{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := toolkit.NewProgressTracker(toolkit.DumpOptions{
+		Context:  ctx,
+		Progress: func(p toolkit.Progress) { reports = append(reports, p) },
+	})
+	tracker.SetTotal(5, 0)
+
+	if err := tracker.Phase(toolkit.PhaseCopy); err != nil {
+		t.Error("error while starting the copy phase", err)
+		return
+	}
+	if _, err := ioutil.ReadAll(tracker.Reader(strings.NewReader("a,b\n1"))); err != nil {
+		t.Error("error while reading the source", err)
+		return
+	}
+	for i := 0; i < toolkit.ProgressInterval+1; i++ {
+		tracker.AddRows(1)
+	}
+	tracker.Done()
+	expected := []toolkit.Progress{
+		{Phase: toolkit.PhaseCopy, TotalBytes: 5},
+		{Phase: toolkit.PhaseCopy, Bytes: 5, TotalBytes: 5, Rows: toolkit.ProgressInterval},
+		{Phase: toolkit.PhaseCopy, Bytes: 5, TotalBytes: 5, Rows: toolkit.ProgressInterval + 1},
+	}
+	if len(reports) != len(expected) {
+		t.Error("expected the reports", expected, "got", reports)
+		return
+	}
+	for i := range expected {
+		if reports[i] != expected[i] {
+			t.Error("expected the report", expected[i], "got", reports[i])
+		}
+	}
+
+	cancel()
+	if _, err := tracker.Reader(strings.NewReader("a")).Read(make([]byte, 1)); err != context.Canceled {
+		t.Error("expected the read to be cancelled. got", err)
+	}
+	if err := tracker.Phase(toolkit.PhaseCleanup); err != context.Canceled {
+		t.Error("expected the phase to be cancelled. got", err)
+	}
+}
+
+func TestProgressTrackerBytes(t *testing.T) {
+	reports := []toolkit.Progress{}
+	tracker := toolkit.NewProgressTracker(toolkit.DumpOptions{
+		Progress: func(p toolkit.Progress) { reports = append(reports, p) },
+	})
+	tracker.Phase(toolkit.PhaseTransfer)
+	src := strings.NewReader(strings.Repeat("a", 2*toolkit.ProgressBytesInterval+10))
+	if _, err := io.Copy(ioutil.Discard, tracker.Reader(src)); err != nil {
+		t.Error("error while reading the source", err)
+		return
+	}
+	if len(reports) != 3 || reports[1].Bytes < toolkit.ProgressBytesInterval || reports[2].Bytes < 2*toolkit.ProgressBytesInterval {
+		t.Error("expected the progress to be reported every", toolkit.ProgressBytesInterval, "bytes. got", reports)
+		return
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+const (
+	//AggregateSum sums the values of a measure
+	AggregateSum = "SUM"
+	//AggregateAvg averages the values of a measure
+	AggregateAvg = "AVG"
+	//AggregateMin finds the minimum value of a measure
+	AggregateMin = "MIN"
+	//AggregateMax finds the maximum value of a measure
+	AggregateMax = "MAX"
+	//AggregateCount counts the values of a measure that are not null
+	AggregateCount = "COUNT"
+	//AggregateCountDistinct counts the distinct values of a measure
+	AggregateCountDistinct = "COUNT DISTINCT"
+)
+
+const (
+	//OperatorEqual matches the values equal to the value of the filter
+	OperatorEqual = "="
+	//OperatorNotEqual matches the values not equal to the value of the filter
+	OperatorNotEqual = "<>"
+	//OperatorGreater matches the values greater than the value of the filter
+	OperatorGreater = ">"
+	//OperatorGreaterOrEqual matches the values greater than or equal to the value of the filter
+	OperatorGreaterOrEqual = ">="
+	//OperatorLess matches the values less than the value of the filter
+	OperatorLess = "<"
+	//OperatorLessOrEqual matches the values less than or equal to the value of the filter
+	OperatorLessOrEqual = "<="
+	//OperatorLike matches the values with the pattern of the filter
+	OperatorLike = "LIKE"
+	//OperatorIn matches the values that are one of the values of the filter
+	OperatorIn = "IN"
+	//OperatorIsNull matches the nulls. The filter has no values
+	OperatorIsNull = "IS NULL"
+	//OperatorIsNotNull matches the values that are not null. The filter has no values
+	OperatorIsNotNull = "IS NOT NULL"
+)
+
+//Measure is a column aggregated in a query
+type Measure struct {
+	//Column is the column aggregated
+	Column interpreter.ColumnNode
+	//Aggregation is the aggregation function like AggregateSum.
+	//Defaults to the measure type of the column if it is an aggregation function else AggregateSum
+	Aggregation string
+	//Alias is the name of the aggregated value in the result. Defaults to the aggregation and the column name like sum_price
+	Alias string
+}
+
+//aggregation returns the aggregation function of the measure
+func (m Measure) aggregation() string {
+	if len(m.Aggregation) != 0 {
+		return strings.ToUpper(m.Aggregation)
+	}
+	if agg := strings.ToUpper(m.Column.MeasureType); isAggregation(agg) {
+		return agg
+	}
+	return AggregateSum
+}
+
+//alias returns the name of the aggregated value of the measure in the result
+func (m Measure) alias() string {
+	if len(m.Alias) != 0 {
+		return m.Alias
+	}
+	return strings.ToLower(strings.Replace(m.aggregation(), " ", "_", -1)) + "_" + m.Column.Name
+}
+
+//Filter filters the rows of a query using the values of a column
+type Filter struct {
+	//Column is the column filtered
+	Column interpreter.ColumnNode
+	//Operator is the operator comparing the column with the values like OperatorEqual
+	Operator string
+	//Values are the values to which the column is compared. OperatorIn can have many values,
+	//OperatorIsNull and OperatorIsNotNull have none and the rest of the operators have one
+	Values []interface{}
+}
+
+//DateRange limits the rows of a query to the dates on the default date field of the table from From until To.
+//From is inclusive and To is exclusive. A zero time leaves that end of the range open
+type DateRange struct {
+	//From is the start of the range
+	From time.Time
+	//To is the end of the range
+	To time.Time
+}
+
+//Order orders the rows of a query
+type Order struct {
+	//Name is the name of the dimension or the alias of the measure by which the rows are ordered
+	Name string
+	//Descending if set orders the rows in the descending order
+	Descending bool
+}
+
+//AggregateQuery is a structured query aggregating the measures of a table grouped by its dimensions
+type AggregateQuery struct {
+	//Table is the table queried
+	Table interpreter.TableNode
+	//Dimensions are the columns by which the measures are grouped
+	Dimensions []interpreter.ColumnNode
+	//Measures are the aggregated columns
+	Measures []Measure
+	//Filters are the filters on the rows of the table. The rows matching all the filters are queried
+	Filters []Filter
+	//DateRange if set limits the rows to a range of dates on the default date field of the table
+	DateRange *DateRange
+	//OrderBy orders the result of the query
+	OrderBy []Order
+	//Limit is the maximum no. of rows in the result. Zero has no limit
+	Limit int
+	//Offset is the no. of rows skipped in the result
+	Offset int
+}
+
+//Build renders the query in the dialect. It returns the sql and its arguments.
+//Identifiers are quoted using the dialect and the values are always passed as arguments
+func (a AggregateQuery) Build(d Dialect) (string, []interface{}, error) {
+	/*
+	 * We will first validate the query
+	 * Then we will render the dimensions and the measures
+	 * Then we will render the filters and the date range
+	 * Then we will render the grouping
+	 * Finally we will render the ordering and the limit
+	 */
+	//validating the query
+	if len(a.Table.Name) == 0 {
+		return "", nil, errors.New("table of the query is not specified")
+	}
+	if len(a.Dimensions) == 0 && len(a.Measures) == 0 {
+		return "", nil, errors.New("query has neither dimensions nor measures")
+	}
+	if a.Limit < 0 || a.Offset < 0 {
+		return "", nil, errors.New("limit and offset of the query can't be negative")
+	}
+
+	//rendering the dimensions and the measures
+	args := []interface{}{}
+	selected := map[string]bool{}
+	fields := []string{}
+	groups := []string{}
+	for _, dim := range a.Dimensions {
+		col := d.QuoteIdentifier(dim.Name)
+		fields = append(fields, col)
+		groups = append(groups, col)
+		selected[dim.Name] = true
+	}
+	for _, m := range a.Measures {
+		agg := m.aggregation()
+		if !isAggregation(agg) {
+			return "", nil, errors.New("unsupported aggregation " + m.Aggregation + " for the measure " + m.Column.Name)
+		}
+		col := d.QuoteIdentifier(m.Column.Name)
+		expr := agg + "(" + col + ")"
+		if agg == AggregateCountDistinct {
+			expr = d.CountDistinct(col)
+		}
+		fields = append(fields, expr+" AS "+d.QuoteIdentifier(m.alias()))
+		selected[m.alias()] = true
+	}
+	var strB strings.Builder
+	strB.WriteString("SELECT " + strings.Join(fields, ", ") + " FROM " + d.QuoteIdentifier(a.Table.Name))
+
+	//rendering the filters and the date range
+	conds := []string{}
+	for _, f := range a.Filters {
+		cond, err := f.render(d, &args)
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, cond)
+	}
+	if a.DateRange != nil {
+		dateCol, err := defaultDateField(a.Table)
+		if err != nil {
+			return "", nil, err
+		}
+		col := d.QuoteIdentifier(dateCol.Name)
+		if !a.DateRange.From.IsZero() {
+			args = append(args, a.DateRange.From)
+			conds = append(conds, col+" >= "+d.Placeholder(len(args)))
+		}
+		if !a.DateRange.To.IsZero() {
+			args = append(args, a.DateRange.To)
+			conds = append(conds, col+" < "+d.Placeholder(len(args)))
+		}
+	}
+	if len(conds) != 0 {
+		strB.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+
+	//rendering the grouping
+	//queries having only the dimensions are grouped as well so that they return the distinct values of the dimensions
+	if len(groups) != 0 {
+		strB.WriteString(" GROUP BY " + strings.Join(groups, ", "))
+	}
+
+	//rendering the ordering and the limit
+	orders := []string{}
+	for _, o := range a.OrderBy {
+		if !selected[o.Name] {
+			return "", nil, errors.New("couldn't find the dimension or measure " + o.Name + " to order the query")
+		}
+		order := d.QuoteIdentifier(o.Name)
+		if o.Descending {
+			order += " DESC"
+		}
+		orders = append(orders, order)
+	}
+	if len(orders) != 0 {
+		strB.WriteString(" ORDER BY " + strings.Join(orders, ", "))
+	}
+	strB.WriteString(d.LimitOffset(a.Limit, a.Offset))
+	return strB.String(), args, nil
+}
+
+//render renders the condition of the filter adding its values to the arguments
+func (f Filter) render(d Dialect, args *[]interface{}) (string, error) {
+	col := d.QuoteIdentifier(f.Column.Name)
+	op := strings.ToUpper(f.Operator)
+	switch op {
+	case OperatorIsNull, OperatorIsNotNull:
+		if len(f.Values) != 0 {
+			return "", errors.New("filter " + op + " on " + f.Column.Name + " can't have values")
+		}
+		return col + " " + op, nil
+	case OperatorIn:
+		if len(f.Values) == 0 {
+			return "", errors.New("filter " + op + " on " + f.Column.Name + " needs at least one value")
+		}
+		placeholders := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			*args = append(*args, v)
+			placeholders[i] = d.Placeholder(len(*args))
+		}
+		return col + " IN (" + strings.Join(placeholders, ", ") + ")", nil
+	case OperatorEqual, OperatorNotEqual, OperatorGreater, OperatorGreaterOrEqual, OperatorLess, OperatorLessOrEqual, OperatorLike:
+		if len(f.Values) != 1 {
+			return "", errors.New("filter " + op + " on " + f.Column.Name + " needs exactly one value")
+		}
+		*args = append(*args, f.Values[0])
+		return col + " " + op + " " + d.Placeholder(len(*args)), nil
+	default:
+		return "", errors.New("unsupported operator " + f.Operator + " in the filter on " + f.Column.Name)
+	}
+}
+
+//defaultDateField returns the default date field of the table
+func defaultDateField(table interpreter.TableNode) (interpreter.ColumnNode, error) {
+	if table.DefaultDateField != nil {
+		return *table.DefaultDateField, nil
+	}
+	for _, col := range table.Columns {
+		if len(table.DefaultDateFieldUID) != 0 && col.UID == table.DefaultDateFieldUID {
+			return col, nil
+		}
+	}
+	return interpreter.ColumnNode{}, errors.New("couldn't find the default date field of the table " + table.Name + " for the date range")
+}
+
+//isAggregation returns true if the given function is a supported aggregation function
+func isAggregation(agg string) bool {
+	switch agg {
+	case AggregateSum, AggregateAvg, AggregateMin, AggregateMax, AggregateCount, AggregateCountDistinct:
+		return true
+	}
+	return false
+}
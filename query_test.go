@@ -0,0 +1,68 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/db-toolkit/datastores/postgres"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestAggregateQuery(t *testing.T) {
+	soldOn := interpreter.ColumnNode{UID: "3", Name: "sold on", DataType: interpreter.DataTypeDate}
+	from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := toolkit.AggregateQuery{
+		Table:      interpreter.TableNode{Name: `sales"2019`, DefaultDateFieldUID: "3", Columns: []interpreter.ColumnNode{soldOn}},
+		Dimensions: []interpreter.ColumnNode{{Name: "region"}},
+		Measures: []toolkit.Measure{
+			{Column: interpreter.ColumnNode{Name: "price"}},
+			{Column: interpreter.ColumnNode{Name: "customer"}, Aggregation: toolkit.AggregateCountDistinct, Alias: "customers"},
+		},
+		Filters: []toolkit.Filter{
+			{Column: interpreter.ColumnNode{Name: "city"}, Operator: toolkit.OperatorIn, Values: []interface{}{"Kochi", "Pune"}},
+			{Column: interpreter.ColumnNode{Name: "discount"}, Operator: toolkit.OperatorIsNull},
+		},
+		DateRange: &toolkit.DateRange{From: from},
+		OrderBy:   []toolkit.Order{{Name: "sum_price", Descending: true}},
+		Limit:     10,
+	}
+	query, args, err := q.Build(postgres.Dialect{})
+	if err != nil {
+		t.Error("error while building the query", err)
+		return
+	}
+	expected := `SELECT "region", SUM("price") AS "sum_price", COUNT(DISTINCT "customer") AS "customers" FROM "sales""2019" ` +
+		`WHERE "city" IN ($1, $2) AND "discount" IS NULL AND "sold on" >= $3 GROUP BY "region" ORDER BY "sum_price" DESC LIMIT 10`
+	if query != expected {
+		t.Error("expected the query", expected, "got", query)
+		return
+	}
+	if !reflect.DeepEqual(args, []interface{}{"Kochi", "Pune", from}) {
+		t.Error("expected the arguments Kochi, Pune and", from, "got", args)
+		return
+	}
+
+	q.OrderBy = []toolkit.Order{{Name: "price"}}
+	if _, _, err := q.Build(postgres.Dialect{}); err == nil {
+		t.Error("expected an error while ordering by a column not in the query")
+		return
+	}
+
+	//queries having only the dimensions are grouped by them
+	q = toolkit.AggregateQuery{
+		Table:      interpreter.TableNode{Name: "sales"},
+		Dimensions: []interpreter.ColumnNode{{Name: "region"}, {Name: "city"}},
+		OrderBy:    []toolkit.Order{{Name: "city"}},
+	}
+	query, _, err = q.Build(postgres.Dialect{})
+	expected = `SELECT "region", "city" FROM "sales" GROUP BY "region", "city" ORDER BY "city"`
+	if err != nil || query != expected {
+		t.Error("expected the query", expected, "got", query, err)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"strings"
+	"unicode"
+)
+
+//QueryToken is a token in a query
+type QueryToken struct {
+	//Value is the value of the token. Quoted identifiers and string literals are unquoted
+	Value string
+	//Quoted is true if the token is a quoted identifier
+	Quoted bool
+	//Literal is true if the token is a string literal
+	Literal bool
+}
+
+//Keyword returns the upper cased value of the token if it is a word else an empty string
+func (q QueryToken) Keyword() string {
+	if q.Quoted || q.Literal {
+		return ""
+	}
+	return strings.ToUpper(q.Value)
+}
+
+//TokenizeQuery splits the query into the words, the quoted identifiers, the string literals and the punctuations.
+//String literals can be quoted with single quotes, escape strings like E'it\'s' or dollar quotes like $$body$$ and $tag$body$tag$.
+//Whitespaces and comments are left out
+func TokenizeQuery(query string) []QueryToken {
+	tokens := []QueryToken{}
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == '"' || r == '\'':
+			var strB strings.Builder
+			for i++; i < len(runes); i++ {
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						//escaped quote
+						strB.WriteRune(r)
+						i++
+						continue
+					}
+					break
+				}
+				strB.WriteRune(runes[i])
+			}
+			tokens = append(tokens, QueryToken{Value: strB.String(), Quoted: r == '"', Literal: r == '\''})
+		case (r == 'E' || r == 'e') && i+1 < len(runes) && runes[i+1] == '\'':
+			var strB strings.Builder
+			for i += 2; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					//backslash escape
+					i++
+					strB.WriteRune(unescapeRune(runes[i]))
+					continue
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						//escaped quote
+						strB.WriteRune('\'')
+						i++
+						continue
+					}
+					break
+				}
+				strB.WriteRune(runes[i])
+			}
+			tokens = append(tokens, QueryToken{Value: strB.String(), Literal: true})
+		case r == '$' && len(dollarTag(runes, i)) != 0:
+			tag := dollarTag(runes, i)
+			n := len([]rune(tag))
+			rest := string(runes[i+n:])
+			end := strings.Index(rest, tag)
+			if end < 0 {
+				//unterminated body runs till the end of the query
+				tokens = append(tokens, QueryToken{Value: rest, Literal: true})
+				i = len(runes)
+				continue
+			}
+			tokens = append(tokens, QueryToken{Value: rest[:end], Literal: true})
+			i += n + len([]rune(rest[:end])) + n - 1
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$':
+			start := i
+			for i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]) || runes[i+1] == '_' || runes[i+1] == '$') {
+				i++
+			}
+			tokens = append(tokens, QueryToken{Value: string(runes[start : i+1])})
+		default:
+			tokens = append(tokens, QueryToken{Value: string(r)})
+		}
+	}
+	return tokens
+}
+
+//ReadOnlyQuery returns true if the query only reads the data. It has to be a select or a with query
+//without the data modifying common table expressions, select into, locking selects and sequence changes.
+//The words in the comments, the quoted identifiers and the string literals are ignored.
+//Queries that can't be told apart are considered to change the data
+func ReadOnlyQuery(query string) bool {
+	tokens := TokenizeQuery(query)
+	if len(tokens) == 0 {
+		return false
+	}
+	if first := tokens[0].Keyword(); first != "SELECT" && first != "WITH" {
+		return false
+	}
+	for _, t := range tokens {
+		switch t.Keyword() {
+		case "INSERT", "UPDATE", "DELETE", "MERGE", "INTO", "FOR", "NEXTVAL", "SETVAL":
+			return false
+		}
+	}
+	return true
+}
+
+//dollarTag returns the opening tag like $$ or $tag$ of the dollar quoted string starting at the index of the runes.
+//An empty string is returned if there isn't a dollar quoted string at the index like for the parameters like $1
+func dollarTag(runes []rune, i int) string {
+	for j := i + 1; j < len(runes); j++ {
+		r := runes[j]
+		switch {
+		case r == '$':
+			return string(runes[i : j+1])
+		case unicode.IsLetter(r) || r == '_' || (unicode.IsDigit(r) && j > i+1):
+			continue
+		}
+		return ""
+	}
+	return ""
+}
+
+//unescapeRune returns the character for the backslash escape sequence of the rune in an escape string
+func unescapeRune(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	}
+	return r
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"reflect"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	queries := map[string]bool{
+		`SELECT count(*) FROM sales`:                                true,
+		`with s AS (SELECT * FROM sales) SELECT * FROM s`:           true,
+		`SELECT 'insert into' AS "update" FROM sales -- delete`:     true,
+		`INSERT INTO sales VALUES (1)`:                              false,
+		`WITH d AS (DELETE FROM sales RETURNING *) SELECT * FROM d`: false,
+		`SELECT * INTO copy FROM sales`:                             false,
+		`SELECT * FROM sales FOR UPDATE`:                            false,
+		`SELECT nextval('sales_id_seq')`:                            false,
+		`/* SELECT */ DELETE FROM sales`:                            false,
+		``:                                                          false,
+		`SELECT $$ delete from sales $$ AS q FROM sales`:            true,
+		`SELECT $body$ it's; INSERT $body$ FROM sales`:              true,
+		`SELECT $$it's$$; DELETE FROM sales`:                        false,
+		`SELECT E'it\'s' AS q; DELETE FROM sales`:                   false,
+		`SELECT e'\\' AS "update" FROM sales`:                       true,
+		`SELECT * FROM sales WHERE id = $1`:                         true,
+	}
+	for query, readOnly := range queries {
+		if toolkit.ReadOnlyQuery(query) != readOnly {
+			t.Error("expected the query", query, "to be read only", readOnly)
+			return
+		}
+	}
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	tokens := toolkit.TokenizeQuery(`SELECT E'it\'s\n', $t$a$$b$t$, $1 -- $$`)
+	expected := []toolkit.QueryToken{
+		{Value: "SELECT"}, {Value: "it's\n", Literal: true}, {Value: ","},
+		{Value: "a$$b", Literal: true}, {Value: ","}, {Value: "$1"},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Error("expected the tokens", expected, "got", tokens)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+)
+
+//DefaultChunkRows is the no. of rows committed in a chunk while resumable ingestion if not specified in the dump options
+const DefaultChunkRows = 100000
+
+//CheckpointTableName is the table in the datastore having the checkpoints of the resumable ingestions
+const CheckpointTableName = "_ingestion_checkpoints"
+
+//fingerprintSize is the no. of bytes read from the start and the end of a file for finding its fingerprint
+const fingerprintSize = 1 << 20
+
+//ResumeTableName returns the name of the table to which the chunks are committed while ingesting the data to the table resumably
+func ResumeTableName(tablename string) string {
+	return tablename + "_resume"
+}
+
+//SourceFingerprint returns the fingerprint of a file for checking whether a resumed ingestion is reading the same file.
+//It is found from the size of the file and the bytes at its start and end so that it is quick even for large files
+func SourceFingerprint(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	h := sha1.New()
+	h.Write([]byte(strconv.FormatInt(info.Size(), 10)))
+	if _, err := io.Copy(h, io.LimitReader(f, fingerprintSize)); err != nil {
+		return "", err
+	}
+	if info.Size() > fingerprintSize {
+		if _, err := io.Copy(h, io.NewSectionReader(f, info.Size()-fingerprintSize, fingerprintSize)); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+//ChunkReader reads the rows from a row reader in chunks. A chunk ends with io.EOF after the given no. of rows are read.
+//The next chunk can be read after calling Next
+type ChunkReader struct {
+	r    RowReader
+	size int64
+	read int64
+	eof  bool
+}
+
+//NewChunkReader returns a chunk reader reading the chunks of given no. of rows from r
+func NewChunkReader(r RowReader, size int64) *ChunkReader {
+	if size <= 0 {
+		size = DefaultChunkRows
+	}
+	return &ChunkReader{r: r, size: size}
+}
+
+//Skip skips the given no. of rows in the source including the malformed ones.
+//It is used for resuming the reading after the rows already committed
+func (c *ChunkReader) Skip(n int64) error {
+	for i := int64(0); i < n; i++ {
+		_, err := c.r.Read()
+		if err == io.EOF {
+			c.eof = true
+			return nil
+		}
+		if _, ok := err.(*csv.ParseError); err != nil && !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+//Next starts the next chunk. It returns false if there are no more rows in the source
+func (c *ChunkReader) Next() bool {
+	c.read = 0
+	return !c.eof
+}
+
+//Rows returns the no. of rows read from the source in the current chunk including the malformed ones
+func (c *ChunkReader) Rows() int64 {
+	return c.read
+}
+
+//Line returns the line at which the last read row started in the source
+func (c *ChunkReader) Line() int {
+	return c.r.Line()
+}
+
+//Raw returns the text of the last read row in the source if the source is a raw row reader
+func (c *ChunkReader) Raw() string {
+	return RawRow(c.r)
+}
+
+//Read reads the next row in the chunk. It returns io.EOF at the end of the chunk
+func (c *ChunkReader) Read() ([]interface{}, error) {
+	if c.eof || c.read >= c.size {
+		return nil, io.EOF
+	}
+	row, err := c.r.Read()
+	if err == io.EOF {
+		c.eof = true
+		return nil, err
+	}
+	c.read++
+	return row, err
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestChunkReader(t *testing.T) {
+	rows, err := toolkit.NewCSVRowReader(strings.NewReader("n\n1\n2\n3\n4\n5\n6\n7\n"), toolkit.CSVDialect{})
+	if err != nil {
+		t.Error("error while reading the header", err)
+		return
+	}
+
+	//skipping the rows of a committed chunk and reading the rest in chunks of 2 rows
+	chunks := toolkit.NewChunkReader(rows, 2)
+	if err := chunks.Skip(2); err != nil {
+		t.Error("error while skipping the committed rows", err)
+		return
+	}
+	read := [][]interface{}{}
+	sizes := []int64{}
+	for chunks.Next() {
+		chunk := []interface{}{}
+		for {
+			row, err := chunks.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Error("error while reading the chunk", err)
+				return
+			}
+			chunk = append(chunk, row[0])
+		}
+		read = append(read, chunk)
+		sizes = append(sizes, chunks.Rows())
+	}
+	expected := [][]interface{}{{"3", "4"}, {"5", "6"}, {"7"}}
+	if !reflect.DeepEqual(read, expected) || !reflect.DeepEqual(sizes, []int64{2, 2, 1}) {
+		t.Error("expected the chunks", expected, "of sizes [2 2 1]. got", read, sizes)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	//DefaultRetryAttempts is the maximum no. of attempts of an operation including the first one if not specified in the retry policy
+	DefaultRetryAttempts = 3
+	//DefaultInitialBackoff is the time waited before the first retry if not specified in the retry policy
+	DefaultInitialBackoff = 100 * time.Millisecond
+	//DefaultMaxBackoff is the maximum time waited between the retries if not specified in the retry policy
+	DefaultMaxBackoff = 5 * time.Second
+	//DefaultBackoffMultiplier is the factor by which the wait grows after each retry if not specified in the retry policy
+	DefaultBackoffMultiplier = 2.0
+)
+
+//RetryPolicy decides how the operations failing with the transient errors are retried.
+//The wait before each retry grows exponentially and is jittered between its half and full so that the clients retrying together spread out
+type RetryPolicy struct {
+	//MaxAttempts is the maximum no. of attempts of an operation including the first one. Defaults to DefaultRetryAttempts
+	MaxAttempts int
+	//InitialBackoff is the time waited before the first retry. Defaults to DefaultInitialBackoff
+	InitialBackoff time.Duration
+	//MaxBackoff is the maximum time waited between the retries. Defaults to DefaultMaxBackoff
+	MaxBackoff time.Duration
+	//Multiplier is the factor by which the wait grows after each retry. Defaults to DefaultBackoffMultiplier
+	Multiplier float64
+	//Sleep waits for the given time or until the context is done. Defaults to a timer. It can be replaced in the tests
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+//IsTransient returns true if the error is transient so that the operation may succeed if tried again.
+//Connection failures, serialization failures, deadlocks and too many connections are transient
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrConnection) || errors.Is(err, ErrSerialization) ||
+		errors.Is(err, ErrDeadlock) || errors.Is(err, ErrTooManyConnections)
+}
+
+//Retryable returns true if the operation that failed with the error can be retried.
+//Idempotent operations are retried for all the transient errors. Other operations are retried only when the error guarantees
+//that the operation wasn't applied like a serialization failure or a deadlock rolling back the transaction,
+//or too many connections failing it before it started. A lost connection can't tell whether the operation was applied,
+//so the operations that aren't idempotent are never retried after it
+func Retryable(err error, idempotent bool) bool {
+	if !IsTransient(err) {
+		return false
+	}
+	if idempotent {
+		return true
+	}
+	return errors.Is(err, ErrSerialization) || errors.Is(err, ErrDeadlock) || errors.Is(err, ErrTooManyConnections)
+}
+
+//withDefaults returns the policy with the defaults set for the fields not specified
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryAttempts
+	}
+	if r.InitialBackoff <= 0 {
+		r.InitialBackoff = DefaultInitialBackoff
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = DefaultMaxBackoff
+	}
+	if r.Multiplier < 1 {
+		r.Multiplier = DefaultBackoffMultiplier
+	}
+	if r.Sleep == nil {
+		r.Sleep = sleep
+	}
+	return r
+}
+
+//Backoff returns the time to be waited before the given retry starting from 1.
+//It grows exponentially up to the maximum backoff and is jittered between its half and full
+func (r RetryPolicy) Backoff(retry int) time.Duration {
+	r = r.withDefaults()
+	backoff := float64(r.InitialBackoff)
+	for i := 1; i < retry && backoff < float64(r.MaxBackoff); i++ {
+		backoff *= r.Multiplier
+	}
+	if backoff > float64(r.MaxBackoff) {
+		backoff = float64(r.MaxBackoff)
+	}
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+//Do runs the operation retrying it as per the policy when it fails with an error retryable for the operation.
+//The retries stop once the context is done. It returns the error of the last attempt
+func (r RetryPolicy) Do(ctx context.Context, idempotent bool, op func() error) error {
+	r = r.withDefaults()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	err := op()
+	for attempt := 1; attempt < r.MaxAttempts && err != nil && Retryable(err, idempotent); attempt++ {
+		if sErr := r.Sleep(ctx, r.Backoff(attempt)); sErr != nil {
+			return err
+		}
+		err = op()
+	}
+	return err
+}
+
+//sleep waits for the given time or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
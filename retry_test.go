@@ -0,0 +1,86 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestRetryPolicyDo(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		idempotent bool
+		attempts   int
+	}{
+		{"connection lost on idempotent", &toolkit.DatastoreError{Kind: toolkit.ErrConnection}, true, 3},
+		{"connection lost on non idempotent", &toolkit.DatastoreError{Kind: toolkit.ErrConnection}, false, 1},
+		{"deadlock on non idempotent", &toolkit.DatastoreError{Kind: toolkit.ErrDeadlock}, false, 3},
+		{"constraint violation", &toolkit.DatastoreError{Kind: toolkit.ErrConstraintViolation}, true, 1},
+		{"unknown error", errors.New("unknown"), true, 1},
+	}
+	for _, c := range cases {
+		waits := 0
+		p := toolkit.RetryPolicy{MaxAttempts: 3, Sleep: func(ctx context.Context, d time.Duration) error {
+			waits++
+			return nil
+		}}
+		attempts := 0
+		err := p.Do(nil, c.idempotent, func() error {
+			attempts++
+			return c.err
+		})
+		if err != c.err {
+			t.Error(c.name, "expected the error of the last attempt. got", err)
+			return
+		}
+		if attempts != c.attempts || waits != c.attempts-1 {
+			t.Error(c.name, "expected", c.attempts, "attempts. got", attempts, "attempts and", waits, "waits")
+			return
+		}
+	}
+
+	//the retries stop once an attempt succeeds
+	p := toolkit.RetryPolicy{MaxAttempts: 5, Sleep: func(ctx context.Context, d time.Duration) error { return nil }}
+	attempts := 0
+	err := p.Do(nil, true, func() error {
+		attempts++
+		if attempts < 2 {
+			return &toolkit.DatastoreError{Kind: toolkit.ErrSerialization}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Error("expected the operation to succeed on the second attempt. got", attempts, "attempts and", err)
+		return
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := toolkit.RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	cases := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{10, time.Second},
+	}
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			b := p.Backoff(c.retry)
+			if b < c.max/2 || b > c.max {
+				t.Error("expected the backoff of the retry", c.retry, "between", c.max/2, "and", c.max, ". got", b)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import "errors"
+
+var (
+	//ErrTableNotFound is the error when the table operated on doesn't exist
+	ErrTableNotFound = errors.New("table doesn't exist")
+	//ErrTableExists is the error when the table to be created by an operation already exists
+	ErrTableExists = errors.New("table already exists")
+	//ErrColumnNotFound is the error when the column operated on doesn't exist in the table
+	ErrColumnNotFound = errors.New("column doesn't exist")
+	//ErrColumnExists is the error when the column to be created by an operation already exists in the table
+	ErrColumnExists = errors.New("column already exists")
+)
+
+//TableError is the error of an operation on a table. The cause can be checked using errors.Is like errors.Is(err, ErrTableNotFound)
+type TableError struct {
+	//Op is the operation like rename, clone, truncate, drop column etc
+	Op string
+	//Table is the name of the table
+	Table string
+	//Err is the cause of the error
+	Err error
+}
+
+//Error returns the error message
+func (t *TableError) Error() string {
+	return t.Op + " " + t.Table + ": " + t.Err.Error()
+}
+
+//Unwrap returns the cause of the error
+func (t *TableError) Unwrap() error {
+	return t.Err
+}
+
+//OrderColumns returns the columns with the given names moved to the front in the given order followed by the rest of the columns.
+//It also returns whether the order of the columns has changed. ErrColumnNotFound is returned if a column with the given name doesn't exist
+func OrderColumns(columns []ColumnInfo, colNames []string) ([]ColumnInfo, bool, error) {
+	colMap := map[string]ColumnInfo{}
+	for _, col := range columns {
+		colMap[col.Name] = col
+	}
+	ordered := []ColumnInfo{}
+	moved := map[string]bool{}
+	for _, name := range colNames {
+		col, ok := colMap[name]
+		if !ok {
+			return nil, false, ErrColumnNotFound
+		}
+		if moved[name] {
+			continue
+		}
+		moved[name] = true
+		ordered = append(ordered, col)
+	}
+	for _, col := range columns {
+		if !moved[col.Name] {
+			ordered = append(ordered, col)
+		}
+	}
+	changed := false
+	for i := range ordered {
+		if ordered[i].Name != columns[i].Name {
+			changed = true
+			break
+		}
+	}
+	return ordered, changed, nil
+}
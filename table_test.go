@@ -0,0 +1,48 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"reflect"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+)
+
+func TestOrderColumns(t *testing.T) {
+	columns := []toolkit.ColumnInfo{}
+	for _, name := range []string{"id", "region", "sold on", "amount"} {
+		columns = append(columns, toolkit.ColumnInfo{Column: toolkit.Column{Name: name}})
+	}
+	cases := []struct {
+		names    []string
+		expected []string
+		changed  bool
+		err      error
+	}{
+		{nil, []string{"id", "region", "sold on", "amount"}, false, nil},
+		{[]string{"id", "region"}, []string{"id", "region", "sold on", "amount"}, false, nil},
+		{[]string{"sold on"}, []string{"sold on", "id", "region", "amount"}, true, nil},
+		{[]string{"amount", "region"}, []string{"amount", "region", "id", "sold on"}, true, nil},
+		{[]string{"region", "region", "id"}, []string{"region", "id", "sold on", "amount"}, true, nil},
+		{[]string{"amount", "id", "sold on", "region"}, []string{"amount", "id", "sold on", "region"}, true, nil},
+		{[]string{"region", "city"}, nil, false, toolkit.ErrColumnNotFound},
+	}
+	for _, c := range cases {
+		ordered, changed, err := toolkit.OrderColumns(columns, c.names)
+		if err != c.err {
+			t.Error("expected the error", c.err, "for ordering the columns as", c.names, "got", err)
+			return
+		}
+		var names []string
+		for _, col := range ordered {
+			names = append(names, col.Name)
+		}
+		if !reflect.DeepEqual(names, c.expected) || changed != c.changed {
+			t.Error("expected the columns", c.expected, "changed", c.changed, "for ordering the columns as", c.names, "got", names, "changed", changed)
+			return
+		}
+	}
+}
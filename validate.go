@@ -0,0 +1,249 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+//ValidateValue validates whether the given value can be stored in the column as per the data type of the column.
+//Empty values are considered as null and are always valid
+func ValidateValue(col interpreter.ColumnNode, value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+	switch col.DataType {
+	case interpreter.DataTypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("expected an integer")
+		}
+	case interpreter.DataTypeFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return errors.New("expected a number")
+		}
+	case interpreter.DataTypeDate:
+		if len(col.DateFormat) == 0 {
+			return nil
+		}
+		if _, err := time.Parse(col.DateFormat, value); err != nil {
+			return errors.New("expected a date in the format " + col.DateFormat)
+		}
+	}
+	return nil
+}
+
+//ValidateRecord validates a record against the columns. It returns nil if the record is valid.
+//The line of the returned row error has to be set by the caller. If the whole record is invalid,
+//the value of the row error is the record joined with the default delimiter
+func ValidateRecord(columns []interpreter.ColumnNode, record []string) *RowError {
+	if len(record) != len(columns) {
+		return &RowError{Value: strings.Join(record, ","), Reason: "expected " + strconv.Itoa(len(columns)) + " fields. got " + strconv.Itoa(len(record))}
+	}
+	for i, col := range columns {
+		if err := ValidateValue(col, record[i]); err != nil {
+			return &RowError{Column: col.Name, Value: record[i], Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+//ValidatedCSV has the result of validating a csv file against the columns
+type ValidatedCSV struct {
+	//Filename is the temporary csv file having the header and the valid rows written in the default dialect
+	Filename string
+	//RejectFilename is the temporary csv file having the invalid rows written in the default dialect.
+	//It is created only in ValidationReject mode.
+	//Each row has the line, column and reason of the row error followed by the fields of the row
+	RejectFilename string
+	//Result has the report of the validation
+	Result DumpResult
+}
+
+//Remove removes the temporary files created while validating the csv
+func (v ValidatedCSV) Remove() {
+	if len(v.Filename) != 0 {
+		os.Remove(v.Filename)
+	}
+	if len(v.RejectFilename) != 0 {
+		os.Remove(v.RejectFilename)
+	}
+}
+
+//ValidateCSV validates the rows in the given csv file against the columns as per the validation mode in the dump options.
+//The file is read in the dialect of the dump options and the valid rows are written to a temporary file in the default dialect
+//with a header so that it can be dumped to the datastore. With ValidationNone the file is only rewritten in the default dialect.
+//Malformed rows are written to the reject file with their text as it was in the file as the first field.
+//In ValidationAbort mode, the validation stops at the first invalid row and an error is returned along with the report
+func ValidateCSV(filename string, columns []interpreter.ColumnNode, opts DumpOptions) (ValidatedCSV, error) {
+	/*
+	 * We will open the source file and create the temporary files
+	 * Then we will write the header
+	 * Then we will iterate through the records and validate them reporting the progress
+	 *		valid records are written to the temporary file
+	 *		invalid records are added to the report and if required written to the reject file
+	 * Finally we will flush the temporary files
+	 */
+	result := ValidatedCSV{}
+
+	//opening the source file and creating the temporary files
+	src, err := os.Open(filename)
+	if err != nil {
+		return result, err
+	}
+	defer src.Close()
+	tracker := NewProgressTracker(opts)
+	if info, err := src.Stat(); err == nil {
+		tracker.SetTotal(info.Size(), 0)
+	}
+	if err := tracker.Phase(PhaseValidate); err != nil {
+		return result, err
+	}
+	r, err := NewCSVReader(tracker.Reader(src), opts.Dialect)
+	if err != nil {
+		return result, err
+	}
+	valid, err := ioutil.TempFile("", "cuttle-valid-*.csv")
+	if err != nil {
+		return result, err
+	}
+	defer valid.Close()
+	result.Filename = valid.Name()
+	validW, _ := NewCSVWriter(valid, CSVDialect{})
+	var rejectW *CSVWriter
+	if opts.Validation == ValidationReject {
+		reject, err := ioutil.TempFile("", "cuttle-reject-*.csv")
+		if err != nil {
+			result.Remove()
+			return result, err
+		}
+		defer reject.Close()
+		result.RejectFilename = reject.Name()
+		rejectW, _ = NewCSVWriter(reject, CSVDialect{})
+	}
+
+	//writing the header
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	if !opts.Dialect.NoHeader {
+		header, err = r.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			result.Remove()
+			return result, err
+		}
+	}
+	validW.WriteStrings(header)
+
+	//iterating through the records
+	for {
+		row, err := r.ReadRow()
+		if err == io.EOF {
+			break
+		}
+		var rErr *RowError
+		if pErr, ok := err.(*csv.ParseError); ok {
+			rErr = &RowError{Line: int64(pErr.StartLine), Value: r.Raw(), Reason: pErr.Err.Error()}
+			row = []interface{}{rErr.Value}
+		} else if err != nil {
+			result.Remove()
+			return result, err
+		} else if opts.Validation != ValidationNone {
+			rErr = ValidateRow(columns, row)
+			if rErr == nil && len(opts.MergeKeys) != 0 {
+				rErr = ValidateMergeKeyValues(opts.MergeKeys, columns, row)
+			}
+			if rErr != nil {
+				rErr.Line = int64(r.Line())
+				if len(rErr.Column) == 0 {
+					//the whole row is reported as it was read
+					rErr.Value = opts.Dialect.JoinRow(row)
+				}
+			}
+		}
+		if err := tracker.AddRows(1); err != nil {
+			result.Remove()
+			return result, err
+		}
+		if rErr == nil {
+			result.Result.RowsLoaded++
+			validW.Write(row)
+			continue
+		}
+		result.Result.AddRowError(*rErr, opts.MaxRowErrors)
+		if opts.Validation == ValidationAbort || opts.Validation == ValidationNone {
+			result.Remove()
+			return result, &DatastoreError{Kind: ErrInvalidValue, Message: "invalid row at line " + strconv.FormatInt(rErr.Line, 10) + " " + rErr.Column + ": " + rErr.Reason}
+		}
+		if rejectW != nil {
+			rejectW.Write(append([]interface{}{rErr.Line, rErr.Column, rErr.Reason}, row...))
+		}
+	}
+
+	//flushing the temporary files
+	tracker.Done()
+	if err := validW.Flush(); err != nil {
+		result.Remove()
+		return result, err
+	}
+	if rejectW != nil {
+		if err := rejectW.Flush(); err != nil {
+			result.Remove()
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+//ValidateRow validates a row read from a data source against the columns. The nulls in the row are always valid.
+//It returns nil if the row is valid. The line of the returned row error has to be set by the caller.
+//If the whole row is invalid, the value of the row error is the row joined with the default delimiter
+func ValidateRow(columns []interpreter.ColumnNode, row []interface{}) *RowError {
+	if len(row) != len(columns) {
+		return &RowError{Value: CSVDialect{}.JoinRow(row), Reason: "expected " + strconv.Itoa(len(columns)) + " fields. got " + strconv.Itoa(len(row))}
+	}
+	for i, col := range columns {
+		if _, ok := row[i].(time.Time); ok && col.DataType == interpreter.DataTypeDate {
+			//typed dates from the source are always valid
+			continue
+		}
+		value, _ := FormatValue(row[i])
+		if err := ValidateValue(col, value); err != nil {
+			return &RowError{Column: col.Name, Value: value, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+//ValidateMergeKeyValues validates whether the values of the merge keys in the row are not null or empty.
+//Rows with null keys never conflict with the existing rows while merging, so they can't be merged.
+//It returns nil if the row has all the merge keys
+func ValidateMergeKeyValues(keys []string, columns []interpreter.ColumnNode, row []interface{}) *RowError {
+	isKey := map[string]bool{}
+	for _, k := range keys {
+		isKey[k] = true
+	}
+	for i, col := range columns {
+		if !isKey[col.Name] || i >= len(row) {
+			continue
+		}
+		if value, null := FormatValue(row[i]); null || len(value) == 0 {
+			return &RowError{Column: col.Name, Reason: "merge key can't be null"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package toolkit_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	toolkit "github.com/cuttle-ai/db-toolkit"
+	"github.com/cuttle-ai/octopus/interpreter"
+)
+
+func TestValidateCSV(t *testing.T) {
+	f, err := ioutil.TempFile("", "groceries-*.csv")
+	if err != nil {
+		t.Error("error while creating the test csv", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("item,quantity\napple,1\nbanana,two\ncarrot\ndates,4294967296\n")
+	f.Close()
+	columns := []interpreter.ColumnNode{
+		{Name: "item"},
+		{Name: "quantity", DataType: interpreter.DataTypeInt},
+	}
+
+	validated, err := toolkit.ValidateCSV(f.Name(), columns, toolkit.DumpOptions{Validation: toolkit.ValidationReject})
+	if err != nil {
+		t.Error("error while validating the csv", err)
+		return
+	}
+	defer validated.Remove()
+	if validated.Result.RowsLoaded != 2 || validated.Result.RowsRejected != 2 {
+		t.Error("expected 2 valid and 2 invalid rows. got", validated.Result.RowsLoaded, validated.Result.RowsRejected)
+		return
+	}
+	if rErr := validated.Result.RowErrors[0]; rErr.Line != 3 || rErr.Column != "quantity" || rErr.Value != "two" {
+		t.Error("expected the row error at line 3 for the column quantity. got", rErr)
+	}
+	if rErr := validated.Result.RowErrors[1]; rErr.Line != 4 || len(rErr.Column) != 0 || rErr.Value != "carrot" {
+		t.Error("expected the row error at line 4 for the whole row with the row as the value. got", rErr)
+	}
+	if len(validated.RejectFilename) == 0 {
+		t.Error("expected a reject file to be created")
+	}
+
+	_, err = toolkit.ValidateCSV(f.Name(), columns, toolkit.DumpOptions{Validation: toolkit.ValidationAbort})
+	if err == nil {
+		t.Error("expected the validation to abort at the first invalid row")
+	}
+}
+
+func TestValidateMergeKeyValues(t *testing.T) {
+	columns := []interpreter.ColumnNode{{Name: "id"}, {Name: "region"}, {Name: "sales"}}
+	keys := []string{"id", "region"}
+	if rErr := toolkit.ValidateMergeKeyValues(keys, columns, []interface{}{"1", "north", nil}); rErr != nil {
+		t.Error("expected the row having the merge keys to be valid. got", rErr)
+		return
+	}
+	if rErr := toolkit.ValidateMergeKeyValues(keys, columns, []interface{}{"1", nil, "10"}); rErr == nil || rErr.Column != "region" {
+		t.Error("expected the row with a null merge key region to be invalid. got", rErr)
+		return
+	}
+	if name := toolkit.MergeIndexName("sales", keys); name != "sales_id_region_merge_keys" {
+		t.Error("expected the merge index to be named after the keys. got", name)
+		return
+	}
+}
+
+func TestValidateCSVRejectFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "groceries-*.csv")
+	if err != nil {
+		t.Error("error while creating the test csv", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("item,quantity\napple,1\nbanana,two\n\"carrot,3\n")
+	f.Close()
+	columns := []interpreter.ColumnNode{
+		{Name: "item"},
+		{Name: "quantity", DataType: interpreter.DataTypeInt},
+	}
+
+	validated, err := toolkit.ValidateCSV(f.Name(), columns, toolkit.DumpOptions{Validation: toolkit.ValidationReject})
+	if err != nil {
+		t.Error("error while validating the csv", err)
+		return
+	}
+	defer validated.Remove()
+	reject, err := os.Open(validated.RejectFilename)
+	if err != nil {
+		t.Error("error while opening the reject file", err)
+		return
+	}
+	defer reject.Close()
+	r, _ := toolkit.NewCSVReader(reject, toolkit.CSVDialect{NoHeader: true})
+	rejected := [][]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Error("error while reading the reject file", err)
+			return
+		}
+		rejected = append(rejected, record)
+	}
+	if int64(len(rejected)) != validated.Result.RowsRejected || len(rejected) != 2 {
+		t.Error("expected the 2 rejected rows in the reject file. got", len(rejected), "rows for", validated.Result.RowsRejected, "rejected")
+		return
+	}
+	if malformed := rejected[1]; malformed[0] != "4" || len(malformed[1]) != 0 || malformed[3] != `"carrot,3` {
+		t.Error("expected the malformed row at line 4 with its text. got", malformed)
+	}
+}